@@ -69,7 +69,8 @@ type (
 		memory bool
 		binary bool
 
-		body interface{}
+		body    interface{}
+		headers map[string]string
 
 		addQueryParams func(Query)
 	}
@@ -80,6 +81,7 @@ type (
 		BaseUrl(baseUrl string) Builder
 		BasicAuth(user, password string) Builder
 		Body(body interface{}) Builder
+		Header(name, value string) Builder
 		Page(page, size int) Builder
 		Columns(columns ...string) Builder
 		Sort(column string, reverse bool) Builder
@@ -186,6 +188,15 @@ func (b *builder) Body(body interface{}) Builder {
 	return b
 }
 
+// Header sets an additional http header on the request
+func (b *builder) Header(name, value string) Builder {
+	if b.headers == nil {
+		b.headers = map[string]string{}
+	}
+	b.headers[name] = value
+	return b
+}
+
 // QueryParameters can be used to set additional query parameters
 // the supplied func will be invoked when Build(), Url() or QueryString() is called
 func (b *builder) QueryParameters(addQueryParams func(Query)) Builder {
@@ -203,12 +214,15 @@ func (b *builder) Build() (*http.Request, error) {
 		body = buf
 	}
 	req, err := http.NewRequest(b.method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
 	req.SetBasicAuth(b.user, b.password)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
-
-	if err != nil {
-		return nil, err
+	for name, value := range b.headers {
+		req.Header.Set(name, value)
 	}
 
 	return req, nil
@@ -0,0 +1,124 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type (
+	// ResponseError is returned when the RabbitMQ api responds with a non-success status code,
+	// Error and Reason contain the error details from the response body, when available.
+	ResponseError struct {
+		StatusCode int    `json:"-"`
+		Status     string `json:"-"`
+		Url        string `json:"-"`
+		Err        string `json:"error"`
+		Reason     string `json:"reason"`
+	}
+)
+
+func (e *ResponseError) Error() string {
+	if len(e.Reason) > 0 {
+		return fmt.Sprintf("request failed: %s, %s ( url: %s )", e.Status, e.Reason, e.Url)
+	}
+	return fmt.Sprintf("request failed: %s ( url: %s )", e.Status, e.Url)
+}
+
+// IsStatus returns true when err is a *ResponseError with the given status code
+func IsStatus(err error, statusCode int) bool {
+	if e, ok := err.(*ResponseError); ok {
+		return e.StatusCode == statusCode
+	}
+	return false
+}
+
+// CheckResponse returns a *ResponseError when the response has a non-success status code.
+// The response body is consumed when an error is returned.
+func CheckResponse(resp *http.Response) error {
+	if isSuccess(resp.StatusCode) {
+		return nil
+	}
+
+	e := &ResponseError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Url:        resp.Request.URL.Redacted(),
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err == nil && len(body) > 0 {
+		// the body is not always json, ignore any decoding errors
+		// and fall back to the plain status in that case
+		json.Unmarshal(body, e)
+	}
+	return e
+}
+
+// DoJson builds and executes the request, and decodes the json response body into result.
+// When result is nil, the response body is discarded. Non-success status codes are returned as a *ResponseError.
+func DoJson(b Builder, result interface{}) (*http.Response, error) {
+	resp, err := Do(b)
+	if err != nil {
+		return resp, err
+	}
+	defer resp.Body.Close()
+
+	traceRequest(resp.Request)
+	traceResponse(resp, err)
+
+	if err := CheckResponse(resp); err != nil {
+		return resp, err
+	}
+
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return resp, nil
+	}
+
+	return resp, json.NewDecoder(resp.Body).Decode(result)
+}
+
+// DoList builds and executes the request, and decodes the json list response into items, which must be a pointer to a slice.
+// Paged responses, e.g. when the --page flag is used, are unwrapped from their "items" field.
+func DoList(b Builder, items interface{}) (*http.Response, error) {
+	data := json.RawMessage{}
+	resp, err := DoJson(b, &data)
+	if err != nil {
+		return resp, err
+	}
+	return resp, DecodeList(data, items)
+}
+
+// DecodeList decodes a json list into items, which must be a pointer to a slice.
+// Paged lists are unwrapped from their "items" field.
+func DecodeList(data []byte, items interface{}) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		paged := struct {
+			Items json.RawMessage `json:"items"`
+		}{}
+		if err := json.Unmarshal(data, &paged); err != nil {
+			return err
+		}
+		data = paged.Items
+	}
+	return json.Unmarshal(data, items)
+}
@@ -0,0 +1,183 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package vhost
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+type (
+	// Change is a difference of an object between two definitions
+	Change struct {
+		Action string      `json:"action"`
+		Type   string      `json:"type"`
+		VHost  string      `json:"vhost,omitempty"`
+		Name   string      `json:"name"`
+		Fields []string    `json:"fields,omitempty"`
+		Source interface{} `json:"source,omitempty"`
+		Target interface{} `json:"target,omitempty"`
+	}
+
+	// object is an object in the definitions, with its identity
+	object struct {
+		Type  string
+		VHost string
+		Name  string
+		Key   string
+		Value map[string]interface{}
+	}
+)
+
+// Compare returns the objects that are added, removed or changed in the target definitions
+// compared to the source definitions, objects are matched by their identity keys
+func Compare(source, target *Definition) []*Change {
+	sourceObjects := source.objects()
+	targetObjects := map[string]*object{}
+	for _, o := range target.objects() {
+		targetObjects[o.Key] = o
+	}
+
+	changes := []*Change{}
+	seen := map[string]bool{}
+	for _, s := range sourceObjects {
+		seen[s.Key] = true
+		t, ok := targetObjects[s.Key]
+		if !ok {
+			changes = append(changes, &Change{Action: Removed, Type: s.Type, VHost: s.VHost, Name: s.Name, Source: s.Value})
+			continue
+		}
+		if fields := changedFields(s.Value, t.Value); len(fields) > 0 {
+			changes = append(changes, &Change{Action: Changed, Type: s.Type, VHost: s.VHost, Name: s.Name, Fields: fields, Source: s.Value, Target: t.Value})
+		}
+	}
+	for _, t := range target.objects() {
+		if !seen[t.Key] {
+			changes = append(changes, &Change{Action: Added, Type: t.Type, VHost: t.VHost, Name: t.Name, Target: t.Value})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		return less(a.Type, b.Type, a.VHost, b.VHost, a.Name, b.Name)
+	})
+	return changes
+}
+
+// objects returns the objects in the definitions with their identity
+func (d *Definition) objects() []*object {
+	objects := []*object{}
+	add := func(kind, vhost, name, key string, value interface{}) {
+		objects = append(objects, &object{Type: kind, VHost: vhost, Name: name, Key: kind + key, Value: toMap(value)})
+	}
+	for _, u := range d.Users {
+		add("user", "", u.Name, u.Key(), u)
+	}
+	for _, v := range d.VHosts {
+		add("vhost", "", v.Name, v.Key(), v)
+	}
+	for _, p := range d.Permissions {
+		add("permission", p.VHost, p.User, p.Key(), p)
+	}
+	for _, p := range d.TopicPermissions {
+		add("topic-permission", p.VHost, p.User+" "+p.Exchange, p.Key(), p)
+	}
+	for _, p := range d.GlobalParameters {
+		add("global-parameter", "", p.Name, p.Key(), p)
+	}
+	for _, p := range d.Parameters {
+		add("parameter", p.VHost, p.Component+"/"+p.Name, p.Key(), p)
+	}
+	for _, p := range d.Policies {
+		add("policy", p.VHost, p.Name, p.Key(), p)
+	}
+	for _, q := range d.Queues {
+		add("queue", q.VHost, q.Name, q.Key(), q)
+	}
+	for _, e := range d.Exchanges {
+		add("exchange", e.VHost, e.Name, e.Key(), e)
+	}
+	for _, b := range d.Bindings {
+		add("binding", b.VHost, b.Name(), b.Key(), b)
+	}
+	return objects
+}
+
+// Name returns a readable name for the binding
+func (b *Binding) Name() string {
+	name := fmt.Sprintf("%s -> %s %s", b.Source, b.DestinationType, b.Destination)
+	if len(b.RoutingKey) > 0 {
+		name += fmt.Sprintf(" (%s)", b.RoutingKey)
+	}
+	if len(b.Arguments) > 0 {
+		name += " " + jsonString(b.Arguments)
+	}
+	return name
+}
+
+// changedFields returns the names of the fields that differ, missing and empty values are equal
+func changedFields(source, target map[string]interface{}) []string {
+	names := map[string]bool{}
+	for name := range source {
+		names[name] = true
+	}
+	for name := range target {
+		names[name] = true
+	}
+
+	fields := []string{}
+	for name := range names {
+		s, t := source[name], target[name]
+		if isEmpty(s) && isEmpty(t) {
+			continue
+		}
+		if !reflect.DeepEqual(s, t) {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// toMap returns the object as it is decoded from its JSON representation
+func toMap(value interface{}) map[string]interface{} {
+	data, _ := json.Marshal(value)
+	m := map[string]interface{}{}
+	json.Unmarshal(data, &m)
+	return m
+}
@@ -0,0 +1,48 @@
+package vhost
+
+import (
+	"testing"
+)
+
+func Test_Compare(t *testing.T) {
+	source := &Definition{
+		Queues: []*Queue{
+			{VHost: "/", Name: "orders", Durable: true, Arguments: map[string]interface{}{"x-queue-type": "quorum"}},
+			{VHost: "/", Name: "same", Durable: true},
+			{VHost: "/", Name: "removed", Durable: true},
+		},
+		Bindings: []*Binding{
+			{VHost: "/", Source: "x", Destination: "orders", DestinationType: "queue", RoutingKey: "a"},
+		},
+	}
+	target := &Definition{
+		Queues: []*Queue{
+			{VHost: "/", Name: "added", Durable: true},
+			{VHost: "/", Name: "same", Durable: true, Arguments: map[string]interface{}{}},
+			{VHost: "/", Name: "orders", Durable: true, Arguments: map[string]interface{}{"x-queue-type": "classic"}},
+		},
+		Bindings: []*Binding{
+			{VHost: "/", Source: "x", Destination: "orders", DestinationType: "queue", RoutingKey: "b"},
+		},
+	}
+
+	expected := []string{
+		"removed binding x -> queue orders (a)",
+		"added binding x -> queue orders (b)",
+		"added queue added",
+		"changed queue orders",
+		"removed queue removed",
+	}
+	changes := Compare(source, target)
+	if len(changes) != len(expected) {
+		t.Fatalf("Compare() returned %d changes, expected %d", len(changes), len(expected))
+	}
+	for i, c := range changes {
+		if actual := c.Action + " " + c.Type + " " + c.Name; actual != expected[i] {
+			t.Errorf("change %d = '%s', expected '%s'", i, actual, expected[i])
+		}
+	}
+	if fields := changes[3].Fields; len(fields) != 1 || fields[0] != "arguments" {
+		t.Errorf("changed fields = %v, expected [arguments]", fields)
+	}
+}
@@ -1,7 +1,3 @@
-//
-// DTO's for the /api/definitions/[vhost] managment api endpoint
-//
-
 /*
 Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
 
@@ -19,39 +15,133 @@ limitations under the License.
 */
 package vhost
 
+import (
+	"encoding/json"
+)
+
+// The types in this file model the definitions documents returned by the GetDefinitions (all vhosts)
+// and GetDefinitionsForVhost (a single vhost) api's. Fields that are not modelled are kept in Extra,
+// so decoding and encoding the definitions does not lose data. Collections that are nil are left out
+// when encoding, empty collections are kept.
 type (
+	// Definition contains the definitions of a vhost, or of all vhosts,
+	// the users, vhosts, permissions and global parameters are only present in the definitions of all vhosts
 	Definition struct {
-		RabbitVersion string        `json:"rabbit_version"`
-		Parameters    []interface{} `json:"parameters"`
-		Policies      []interface{} `json:"policies"`
-		Queues        []*Queue      `json:"queues"`
-		Exchanges     []*Exchange   `json:"exchanges"`
-		Bindings      []*Binding    `json:"bindings"`
+		RabbitVersion    string             `json:"rabbit_version,omitempty"`
+		RabbitMQVersion  string             `json:"rabbitmq_version,omitempty"`
+		ProductName      string             `json:"product_name,omitempty"`
+		ProductVersion   string             `json:"product_version,omitempty"`
+		Users            []*User            `json:"users,omitempty"`
+		VHosts           []*VHost           `json:"vhosts,omitempty"`
+		Permissions      []*Permission      `json:"permissions,omitempty"`
+		TopicPermissions []*TopicPermission `json:"topic_permissions,omitempty"`
+		Parameters       []*Parameter       `json:"parameters,omitempty"`
+		GlobalParameters []*GlobalParameter `json:"global_parameters,omitempty"`
+		Policies         []*Policy          `json:"policies,omitempty"`
+		Queues           []*Queue           `json:"queues,omitempty"`
+		Exchanges        []*Exchange        `json:"exchanges,omitempty"`
+		Bindings         []*Binding         `json:"bindings,omitempty"`
+
+		Extra map[string]json.RawMessage `json:"-"`
+	}
+
+	User struct {
+		Name             string   `json:"name"`
+		PasswordHash     string   `json:"password_hash,omitempty"`
+		HashingAlgorithm string   `json:"hashing_algorithm"`
+		Tags             UserTags `json:"tags"`
+		Limits           *Limits  `json:"limits,omitempty"`
+
+		Extra map[string]json.RawMessage `json:"-"`
+	}
+
+	// UserTags are the tags of a user, RabbitMQ 3.9 and older use a comma separated string,
+	// later versions use a list, the tags are encoded in the format they were decoded from
+	UserTags struct {
+		Values []string
+		list   bool
+	}
+
+	VHost struct {
+		Name             string   `json:"name"`
+		Description      *string  `json:"description,omitempty"`
+		Tags             []string `json:"tags,omitempty"`
+		DefaultQueueType *string  `json:"default_queue_type,omitempty"`
+		Limits           *Limits  `json:"limits,omitempty"`
+
+		Extra map[string]json.RawMessage `json:"-"`
+	}
+
+	// Limits are the limits of a user or vhost, like max-connections and max-queues,
+	// RabbitMQ exports empty limits as an empty list, the limits are encoded in the format they were decoded from
+	Limits struct {
+		Values map[string]int64
+		list   bool
+	}
+
+	Permission struct {
+		User      string `json:"user"`
+		VHost     string `json:"vhost"`
+		Configure string `json:"configure"`
+		Write     string `json:"write"`
+		Read      string `json:"read"`
+
+		Extra map[string]json.RawMessage `json:"-"`
+	}
+
+	TopicPermission struct {
+		User     string `json:"user"`
+		VHost    string `json:"vhost"`
+		Exchange string `json:"exchange"`
+		Write    string `json:"write"`
+		Read     string `json:"read"`
+
+		Extra map[string]json.RawMessage `json:"-"`
+	}
+
+	Policy struct {
+		VHost      string            `json:"vhost,omitempty"`
+		Name       string            `json:"name"`
+		Pattern    string            `json:"pattern"`
+		ApplyTo    string            `json:"apply-to"`
+		Priority   int               `json:"priority"`
+		Definition *PolicyDefinition `json:"definition"`
+
+		Extra map[string]json.RawMessage `json:"-"`
 	}
 
 	Queue struct {
+		VHost      string                 `json:"vhost,omitempty"`
 		Name       string                 `json:"name"`
+		Type       string                 `json:"type,omitempty"`
 		Durable    bool                   `json:"durable"`
 		AutoDelete bool                   `json:"auto_delete"`
-		Arguments  map[string]interface{} `json:"arguments"`
+		Arguments  map[string]interface{} `json:"arguments,omitempty"`
+
+		Extra map[string]json.RawMessage `json:"-"`
 	}
 
 	Exchange struct {
+		VHost      string                 `json:"vhost,omitempty"`
 		Name       string                 `json:"name"`
 		Type       string                 `json:"type"`
 		Durable    bool                   `json:"durable"`
 		AutoDelete bool                   `json:"auto_delete"`
 		Internal   bool                   `json:"internal"`
-		Arguments  map[string]interface{} `json:"arguments"`
+		Arguments  map[string]interface{} `json:"arguments,omitempty"`
+
+		Extra map[string]json.RawMessage `json:"-"`
 	}
 
 	Binding struct {
-		VHost           string                 `json:"vhost"`
+		VHost           string                 `json:"vhost,omitempty"`
 		Source          string                 `json:"source"`
 		Destination     string                 `json:"destination"`
 		DestinationType string                 `json:"destination_type"`
-		PropertiesKey   string                 `json:"properties_key"`
+		PropertiesKey   string                 `json:"properties_key,omitempty"`
 		RoutingKey      string                 `json:"routing_key"`
-		Arguments       map[string]interface{} `json:"arguments"`
+		Arguments       map[string]interface{} `json:"arguments,omitempty"`
+
+		Extra map[string]json.RawMessage `json:"-"`
 	}
 )
@@ -0,0 +1,146 @@
+package vhost
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const clusterDefinition = `{
+	"rabbit_version": "3.9.13",
+	"rabbitmq_version": "3.9.13",
+	"product_name": "RabbitMQ",
+	"product_version": "3.9.13",
+	"explanation": "unknown top level field",
+	"users": [
+		{"name": "admin", "password_hash": "hash", "hashing_algorithm": "rabbit_password_hashing_sha256", "tags": ["administrator"], "limits": {}},
+		{"name": "app", "password_hash": "hash", "hashing_algorithm": "rabbit_password_hashing_sha256", "tags": "monitoring,management", "limits": [], "future": true}
+	],
+	"vhosts": [
+		{"name": "/", "description": "default", "tags": [], "default_queue_type": "quorum", "limits": {"max-queues": 10}, "metadata": {"x": 1}}
+	],
+	"permissions": [
+		{"user": "app", "vhost": "/", "configure": ".*", "write": ".*", "read": ".*", "extra": "p"}
+	],
+	"topic_permissions": [
+		{"user": "app", "vhost": "/", "exchange": "amq.topic", "write": ".*", "read": ".*"}
+	],
+	"parameters": [
+		{"vhost": "/", "component": "shovel", "name": "single", "value": {"src-protocol": "amqp091", "src-uri": "amqp://", "src-queue": "a", "dest-protocol": "amqp091", "dest-uri": "amqp://remote", "dest-queue": "b", "dest-add-timestamp-header": true, "unknown": [1, 2]}},
+		{"vhost": "/", "component": "shovel", "name": "list", "value": {"src-uri": ["amqp://a", "amqp://b"], "src-queue": "a", "dest-uri": ["amqp://c"], "dest-queue": "b", "src-delete-after": "never"}},
+		{"vhost": "/", "component": "federation-upstream", "name": "upstream", "value": {"uri": "amqp://upstream", "prefetch-count": 1000, "ack-mode": "on-confirm", "bind-nowait": false}},
+		{"vhost": "/", "component": "other", "name": "generic", "value": {"anything": [1, "two", null]}, "hidden": 1}
+	],
+	"global_parameters": [
+		{"name": "cluster_name", "value": "rabbit@localhost", "unknown": "g"}
+	],
+	"policies": [
+		{"vhost": "/", "name": "ha", "pattern": ".*", "apply-to": "queues", "priority": 0, "definition": {"ha-mode": "exactly", "ha-params": 2, "message-ttl": "wrong", "max-length": null, "x-custom": true}, "future": {}}
+	],
+	"queues": [
+		{"name": "q", "vhost": "/", "durable": true, "auto_delete": false, "arguments": {"x-queue-type": "quorum"}, "type": "quorum", "extra": 1}
+	],
+	"exchanges": [
+		{"name": "x", "vhost": "/", "type": "topic", "durable": true, "auto_delete": false, "internal": false, "arguments": {}, "extra": "e"}
+	],
+	"bindings": [
+		{"source": "x", "vhost": "/", "destination": "q", "destination_type": "queue", "routing_key": "#", "arguments": {}, "extra": "b"}
+	]
+}`
+
+const vhostDefinition = `{
+	"rabbit_version": "3.9.13",
+	"parameters": [
+		{"component": "shovel", "name": "single", "value": {"src-uri": "amqp://", "src-queue": "a", "dest-uri": "amqp://remote", "dest-queue": "b"}}
+	],
+	"policies": [
+		{"name": "ttl", "pattern": ".*", "apply-to": "queues", "priority": 1, "definition": {"message-ttl": 1000}}
+	],
+	"queues": [
+		{"name": "q", "durable": true, "auto_delete": false, "arguments": {}}
+	],
+	"exchanges": [],
+	"bindings": [],
+	"unknown": {"nested": [true]}
+}`
+
+func normalize(t *testing.T, data []byte) interface{} {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	return v
+}
+
+func roundTrip(t *testing.T, data string) *Definition {
+	d := &Definition{}
+	if err := json.Unmarshal([]byte(data), d); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	encoded, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	if expected, actual := normalize(t, []byte(data)), normalize(t, encoded); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("round trip changed the definitions\nexpected: %v\nactual:   %v", expected, actual)
+	}
+	return d
+}
+
+func Test_Definition_RoundTrip(t *testing.T) {
+	d := roundTrip(t, clusterDefinition)
+
+	if tags := d.Users[1].Tags.Values; !reflect.DeepEqual(tags, []string{"monitoring", "management"}) {
+		t.Errorf("user tags = %v, expected [monitoring management]", tags)
+	}
+	if shovel := d.Parameters[0].Shovel(); shovel == nil || !reflect.DeepEqual(shovel.SrcUri.Values, []string{"amqp://"}) {
+		t.Errorf("shovel src-uri was not decoded from a string")
+	}
+	if shovel := d.Parameters[1].Shovel(); shovel == nil || len(shovel.SrcUri.Values) != 2 {
+		t.Errorf("shovel src-uri was not decoded from a list")
+	}
+	if upstream := d.Parameters[2].FederationUpstream(); upstream == nil || *upstream.PrefetchCount != 1000 {
+		t.Errorf("federation upstream was not decoded")
+	}
+	if d.Parameters[3].Shovel() != nil || d.Parameters[3].FederationUpstream() != nil {
+		t.Errorf("generic parameter was decoded as a typed value")
+	}
+
+	definition := d.Policies[0].Definition
+	if definition.HaMode == nil || *definition.HaMode != "exactly" {
+		t.Errorf("policy ha-mode was not decoded")
+	}
+	if definition.MessageTtl != nil {
+		t.Errorf("policy message-ttl with a string value was decoded")
+	}
+	if _, ok := definition.Extra["message-ttl"]; !ok {
+		t.Errorf("policy message-ttl with a string value was not kept")
+	}
+	if definition.Map()["x-custom"] != true {
+		t.Errorf("policy x-custom was not kept")
+	}
+}
+
+func Test_Definition_RoundTrip_VHost(t *testing.T) {
+	d := roundTrip(t, vhostDefinition)
+
+	if d.Users != nil {
+		t.Errorf("users = %v, expected nil", d.Users)
+	}
+	if d.Exchanges == nil {
+		t.Errorf("exchanges = nil, expected an empty list")
+	}
+	if ttl := d.Policies[0].Definition.MessageTtl; ttl == nil || *ttl != 1000 {
+		t.Errorf("policy message-ttl was not decoded")
+	}
+}
+
+func Test_User_WithoutPasswordHash(t *testing.T) {
+	data, err := json.Marshal(&User{Name: "app", HashingAlgorithm: "rabbit_password_hashing_sha256"})
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	if _, ok := normalize(t, data).(map[string]interface{})["password_hash"]; ok {
+		t.Errorf("Marshal() = %s, expected no password_hash", data)
+	}
+}
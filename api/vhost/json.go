@@ -0,0 +1,277 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package vhost
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// unmarshalWithExtra decodes the JSON object into v, a pointer to a struct without an UnmarshalJSON method,
+// and returns the fields of the object that are not fields of the struct
+func unmarshalWithExtra(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(v).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		if name, _ := jsonField(t.Field(i)); len(name) > 0 {
+			delete(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// marshalWithExtra encodes v, a struct without a MarshalJSON method, as a JSON object in field order,
+// followed by the extra fields in sorted order. Fields with the omitempty option are left out when they are
+// nil collections or pointers, or zero values of other types; unlike encoding/json, empty collections are kept
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	value := reflect.ValueOf(v)
+	t := value.Type()
+
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	known := map[string]bool{}
+	write := func(name string, data []byte) {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(data)
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		name, omitEmpty := jsonField(t.Field(i))
+		if len(name) == 0 {
+			continue
+		}
+		field := value.Field(i)
+		if omitEmpty && isOmitted(field) {
+			// only PolicyDefinition keeps modelled keys in extra, when their value is null or has an
+			// unexpected type, the other types fail to decode such values, so extra never has their fields
+			continue
+		}
+		known[name] = true
+		data, err := json.Marshal(field.Interface())
+		if err != nil {
+			return nil, err
+		}
+		write(name, data)
+	}
+
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		if !known[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		write(name, extra[name])
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonField returns the JSON name of an exported struct field and whether it has the omitempty option,
+// the name is empty for unexported fields and fields that are not encoded
+func jsonField(f reflect.StructField) (string, bool) {
+	if len(f.PkgPath) > 0 {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if len(name) == 0 {
+		name = f.Name
+	}
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			return name, true
+		}
+	}
+	return name, false
+}
+
+func isOmitted(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+func (d *Definition) UnmarshalJSON(data []byte) (err error) {
+	type plain Definition
+	d.Extra, err = unmarshalWithExtra(data, (*plain)(d))
+	return err
+}
+
+func (d Definition) MarshalJSON() ([]byte, error) {
+	type plain Definition
+	return marshalWithExtra(plain(d), d.Extra)
+}
+
+func (u *User) UnmarshalJSON(data []byte) (err error) {
+	type plain User
+	u.Extra, err = unmarshalWithExtra(data, (*plain)(u))
+	return err
+}
+
+func (u User) MarshalJSON() ([]byte, error) {
+	type plain User
+	return marshalWithExtra(plain(u), u.Extra)
+}
+
+func (t *UserTags) UnmarshalJSON(data []byte) error {
+	var tags string
+	if err := json.Unmarshal(data, &tags); err == nil {
+		t.Values, t.list = []string{}, false
+		for _, tag := range strings.Split(tags, ",") {
+			if tag = strings.TrimSpace(tag); len(tag) > 0 {
+				t.Values = append(t.Values, tag)
+			}
+		}
+		return nil
+	}
+	t.list = true
+	return json.Unmarshal(data, &t.Values)
+}
+
+func (t UserTags) MarshalJSON() ([]byte, error) {
+	if t.list {
+		if t.Values == nil {
+			return []byte("[]"), nil
+		}
+		return json.Marshal(t.Values)
+	}
+	return json.Marshal(strings.Join(t.Values, ","))
+}
+
+func (l *Limits) UnmarshalJSON(data []byte) error {
+	var list []interface{}
+	if err := json.Unmarshal(data, &list); err == nil && len(list) == 0 {
+		l.Values, l.list = map[string]int64{}, true
+		return nil
+	}
+	l.list = false
+	return json.Unmarshal(data, &l.Values)
+}
+
+func (l Limits) MarshalJSON() ([]byte, error) {
+	if l.list && len(l.Values) == 0 {
+		return []byte("[]"), nil
+	}
+	if l.Values == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(l.Values)
+}
+
+func (v *VHost) UnmarshalJSON(data []byte) (err error) {
+	type plain VHost
+	v.Extra, err = unmarshalWithExtra(data, (*plain)(v))
+	return err
+}
+
+func (v VHost) MarshalJSON() ([]byte, error) {
+	type plain VHost
+	return marshalWithExtra(plain(v), v.Extra)
+}
+
+func (p *Permission) UnmarshalJSON(data []byte) (err error) {
+	type plain Permission
+	p.Extra, err = unmarshalWithExtra(data, (*plain)(p))
+	return err
+}
+
+func (p Permission) MarshalJSON() ([]byte, error) {
+	type plain Permission
+	return marshalWithExtra(plain(p), p.Extra)
+}
+
+func (p *TopicPermission) UnmarshalJSON(data []byte) (err error) {
+	type plain TopicPermission
+	p.Extra, err = unmarshalWithExtra(data, (*plain)(p))
+	return err
+}
+
+func (p TopicPermission) MarshalJSON() ([]byte, error) {
+	type plain TopicPermission
+	return marshalWithExtra(plain(p), p.Extra)
+}
+
+func (p *Policy) UnmarshalJSON(data []byte) (err error) {
+	type plain Policy
+	p.Extra, err = unmarshalWithExtra(data, (*plain)(p))
+	return err
+}
+
+func (p Policy) MarshalJSON() ([]byte, error) {
+	type plain Policy
+	return marshalWithExtra(plain(p), p.Extra)
+}
+
+func (q *Queue) UnmarshalJSON(data []byte) (err error) {
+	type plain Queue
+	q.Extra, err = unmarshalWithExtra(data, (*plain)(q))
+	return err
+}
+
+func (q Queue) MarshalJSON() ([]byte, error) {
+	type plain Queue
+	return marshalWithExtra(plain(q), q.Extra)
+}
+
+func (e *Exchange) UnmarshalJSON(data []byte) (err error) {
+	type plain Exchange
+	e.Extra, err = unmarshalWithExtra(data, (*plain)(e))
+	return err
+}
+
+func (e Exchange) MarshalJSON() ([]byte, error) {
+	type plain Exchange
+	return marshalWithExtra(plain(e), e.Extra)
+}
+
+func (b *Binding) UnmarshalJSON(data []byte) (err error) {
+	type plain Binding
+	b.Extra, err = unmarshalWithExtra(data, (*plain)(b))
+	return err
+}
+
+func (b Binding) MarshalJSON() ([]byte, error) {
+	type plain Binding
+	return marshalWithExtra(plain(b), b.Extra)
+}
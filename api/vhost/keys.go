@@ -0,0 +1,63 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package vhost
+
+import (
+	"encoding/json"
+)
+
+// Key identifies the user
+func (u *User) Key() string { return key(u.Name) }
+
+// Key identifies the vhost
+func (v *VHost) Key() string { return key(v.Name) }
+
+// Key identifies the permission by vhost and user
+func (p *Permission) Key() string { return key(p.VHost, p.User) }
+
+// Key identifies the topic permission by vhost, user and exchange
+func (p *TopicPermission) Key() string { return key(p.VHost, p.User, p.Exchange) }
+
+// Key identifies the parameter by vhost, component and name
+func (p *Parameter) Key() string { return key(p.VHost, p.Component, p.Name) }
+
+// Key identifies the global parameter
+func (p *GlobalParameter) Key() string { return key(p.Name) }
+
+// Key identifies the policy by vhost and name
+func (p *Policy) Key() string { return key(p.VHost, p.Name) }
+
+// Key identifies the queue by vhost and name
+func (q *Queue) Key() string { return key(q.VHost, q.Name) }
+
+// Key identifies the exchange by vhost and name
+func (e *Exchange) Key() string { return key(e.VHost, e.Name) }
+
+// Key identifies the binding by vhost, source, destination, routing key and arguments,
+// a binding cannot be changed, a binding with other arguments is another binding
+func (b *Binding) Key() string {
+	arguments := "{}"
+	if len(b.Arguments) > 0 {
+		arguments = jsonString(b.Arguments)
+	}
+	return key(b.VHost, b.Source, b.DestinationType, b.Destination, b.RoutingKey, arguments)
+}
+
+// key joins the parts into an unambiguous key
+func key(parts ...string) string {
+	data, _ := json.Marshal(parts)
+	return string(data)
+}
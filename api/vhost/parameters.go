@@ -0,0 +1,186 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package vhost
+
+import (
+	"encoding/json"
+)
+
+type (
+	// Parameter is a runtime parameter of a component in a vhost, the value of shovel
+	// and federation-upstream parameters is a *ShovelValue or a *FederationUpstreamValue,
+	// the value of other components is decoded as generic JSON
+	Parameter struct {
+		VHost     string      `json:"vhost,omitempty"`
+		Component string      `json:"component"`
+		Name      string      `json:"name"`
+		Value     interface{} `json:"value"`
+
+		Extra map[string]json.RawMessage `json:"-"`
+	}
+
+	// GlobalParameter is a global runtime parameter, like cluster_name
+	GlobalParameter struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+
+		Extra map[string]json.RawMessage `json:"-"`
+	}
+
+	// ShovelValue is the value of a dynamic shovel parameter
+	ShovelValue struct {
+		SrcProtocol            string      `json:"src-protocol,omitempty"`
+		SrcUri                 URIs        `json:"src-uri"`
+		SrcQueue               string      `json:"src-queue,omitempty"`
+		SrcExchange            string      `json:"src-exchange,omitempty"`
+		SrcExchangeKey         string      `json:"src-exchange-key,omitempty"`
+		SrcAddress             string      `json:"src-address,omitempty"`
+		SrcPrefetchCount       *int64      `json:"src-prefetch-count,omitempty"`
+		SrcDeleteAfter         interface{} `json:"src-delete-after,omitempty"`
+		DestProtocol           string      `json:"dest-protocol,omitempty"`
+		DestUri                URIs        `json:"dest-uri"`
+		DestQueue              string      `json:"dest-queue,omitempty"`
+		DestExchange           string      `json:"dest-exchange,omitempty"`
+		DestExchangeKey        string      `json:"dest-exchange-key,omitempty"`
+		DestAddress            string      `json:"dest-address,omitempty"`
+		DestAddForwardHeaders  *bool       `json:"dest-add-forward-headers,omitempty"`
+		DestAddTimestampHeader *bool       `json:"dest-add-timestamp-header,omitempty"`
+		AckMode                string      `json:"ack-mode,omitempty"`
+		ReconnectDelay         *int64      `json:"reconnect-delay,omitempty"`
+
+		Extra map[string]json.RawMessage `json:"-"`
+	}
+
+	// FederationUpstreamValue is the value of a federation-upstream parameter
+	FederationUpstreamValue struct {
+		Uri            URIs   `json:"uri"`
+		PrefetchCount  *int64 `json:"prefetch-count,omitempty"`
+		ReconnectDelay *int64 `json:"reconnect-delay,omitempty"`
+		AckMode        string `json:"ack-mode,omitempty"`
+		TrustUserId    *bool  `json:"trust-user-id,omitempty"`
+		Exchange       string `json:"exchange,omitempty"`
+		MaxHops        *int64 `json:"max-hops,omitempty"`
+		Expires        *int64 `json:"expires,omitempty"`
+		MessageTtl     *int64 `json:"message-ttl,omitempty"`
+		Queue          string `json:"queue,omitempty"`
+		ConsumerTag    string `json:"consumer-tag,omitempty"`
+
+		Extra map[string]json.RawMessage `json:"-"`
+	}
+
+	// URIs is a single uri, or a list of uris, the uris are encoded in the format they were decoded from
+	URIs struct {
+		Values []string
+		list   bool
+	}
+)
+
+// Shovel returns the value of a shovel parameter, or nil for other components
+func (p *Parameter) Shovel() *ShovelValue {
+	v, _ := p.Value.(*ShovelValue)
+	return v
+}
+
+// FederationUpstream returns the value of a federation-upstream parameter, or nil for other components
+func (p *Parameter) FederationUpstream() *FederationUpstreamValue {
+	v, _ := p.Value.(*FederationUpstreamValue)
+	return v
+}
+
+func (p *Parameter) UnmarshalJSON(data []byte) (err error) {
+	type plain Parameter
+	if p.Extra, err = unmarshalWithExtra(data, (*plain)(p)); err != nil {
+		return err
+	}
+
+	var raw struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil || len(raw.Value) == 0 {
+		return err
+	}
+	switch p.Component {
+	case "shovel":
+		value := &ShovelValue{}
+		if err := json.Unmarshal(raw.Value, value); err == nil {
+			p.Value = value
+		}
+	case "federation-upstream":
+		value := &FederationUpstreamValue{}
+		if err := json.Unmarshal(raw.Value, value); err == nil {
+			p.Value = value
+		}
+	}
+	return nil
+}
+
+func (p Parameter) MarshalJSON() ([]byte, error) {
+	type plain Parameter
+	return marshalWithExtra(plain(p), p.Extra)
+}
+
+func (p *GlobalParameter) UnmarshalJSON(data []byte) (err error) {
+	type plain GlobalParameter
+	p.Extra, err = unmarshalWithExtra(data, (*plain)(p))
+	return err
+}
+
+func (p GlobalParameter) MarshalJSON() ([]byte, error) {
+	type plain GlobalParameter
+	return marshalWithExtra(plain(p), p.Extra)
+}
+
+func (v *ShovelValue) UnmarshalJSON(data []byte) (err error) {
+	type plain ShovelValue
+	v.Extra, err = unmarshalWithExtra(data, (*plain)(v))
+	return err
+}
+
+func (v ShovelValue) MarshalJSON() ([]byte, error) {
+	type plain ShovelValue
+	return marshalWithExtra(plain(v), v.Extra)
+}
+
+func (v *FederationUpstreamValue) UnmarshalJSON(data []byte) (err error) {
+	type plain FederationUpstreamValue
+	v.Extra, err = unmarshalWithExtra(data, (*plain)(v))
+	return err
+}
+
+func (v FederationUpstreamValue) MarshalJSON() ([]byte, error) {
+	type plain FederationUpstreamValue
+	return marshalWithExtra(plain(v), v.Extra)
+}
+
+func (u *URIs) UnmarshalJSON(data []byte) error {
+	var uri string
+	if err := json.Unmarshal(data, &uri); err == nil {
+		u.Values, u.list = []string{uri}, false
+		return nil
+	}
+	u.list = true
+	return json.Unmarshal(data, &u.Values)
+}
+
+func (u URIs) MarshalJSON() ([]byte, error) {
+	if !u.list && len(u.Values) == 1 {
+		return json.Marshal(u.Values[0])
+	}
+	if u.Values == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(u.Values)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package vhost
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// PolicyDefinition contains the keys of a policy or operator policy definition,
+// keys that are not modelled, or that have a null value or a value of another type, are kept in Extra
+type PolicyDefinition struct {
+	AlternateExchange         *string     `json:"alternate-exchange,omitempty"`
+	DeadLetterExchange        *string     `json:"dead-letter-exchange,omitempty"`
+	DeadLetterRoutingKey      *string     `json:"dead-letter-routing-key,omitempty"`
+	DeadLetterStrategy        *string     `json:"dead-letter-strategy,omitempty"`
+	DeliveryLimit             *int64      `json:"delivery-limit,omitempty"`
+	Expires                   *int64      `json:"expires,omitempty"`
+	FederationUpstream        *string     `json:"federation-upstream,omitempty"`
+	FederationUpstreamSet     *string     `json:"federation-upstream-set,omitempty"`
+	HaMode                    *string     `json:"ha-mode,omitempty"`
+	HaParams                  interface{} `json:"ha-params,omitempty"`
+	HaPromoteOnFailure        *string     `json:"ha-promote-on-failure,omitempty"`
+	HaPromoteOnShutdown       *string     `json:"ha-promote-on-shutdown,omitempty"`
+	HaSyncBatchSize           *int64      `json:"ha-sync-batch-size,omitempty"`
+	HaSyncMode                *string     `json:"ha-sync-mode,omitempty"`
+	MaxAge                    *string     `json:"max-age,omitempty"`
+	MaxInMemoryBytes          *int64      `json:"max-in-memory-bytes,omitempty"`
+	MaxInMemoryLength         *int64      `json:"max-in-memory-length,omitempty"`
+	MaxLength                 *int64      `json:"max-length,omitempty"`
+	MaxLengthBytes            *int64      `json:"max-length-bytes,omitempty"`
+	MessageTtl                *int64      `json:"message-ttl,omitempty"`
+	Overflow                  *string     `json:"overflow,omitempty"`
+	QueueLeaderLocator        *string     `json:"queue-leader-locator,omitempty"`
+	QueueMasterLocator        *string     `json:"queue-master-locator,omitempty"`
+	QueueMode                 *string     `json:"queue-mode,omitempty"`
+	QueueVersion              *int64      `json:"queue-version,omitempty"`
+	StreamMaxSegmentSizeBytes *int64      `json:"stream-max-segment-size-bytes,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// NewPolicyDefinition returns the typed policy definition of the keys and values
+func NewPolicyDefinition(definition map[string]interface{}) *PolicyDefinition {
+	data, _ := json.Marshal(definition)
+	d := &PolicyDefinition{}
+	d.UnmarshalJSON(data)
+	return d
+}
+
+// Map returns the keys and values of the policy definition
+func (d *PolicyDefinition) Map() map[string]interface{} {
+	m := map[string]interface{}{}
+	if d == nil {
+		return m
+	}
+	data, _ := json.Marshal(d)
+	json.Unmarshal(data, &m)
+	return m
+}
+
+// UnmarshalJSON decodes the policy definition, a value with an unexpected type
+// does not fail the decoding, but is kept in Extra, so it can be validated later
+func (d *PolicyDefinition) UnmarshalJSON(data []byte) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*d = PolicyDefinition{}
+	value := reflect.ValueOf(d).Elem()
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _ := jsonField(t.Field(i))
+		raw, ok := fields[name]
+		if len(name) == 0 || !ok {
+			continue
+		}
+		if string(bytes.TrimSpace(raw)) == "null" {
+			// a null value decodes as a missing key, so it is kept in extra to encode it again
+			continue
+		}
+		field := value.Field(i)
+		if err := json.Unmarshal(raw, field.Addr().Interface()); err != nil {
+			// the decoder may have allocated the pointer before it failed
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		delete(fields, name)
+	}
+	if len(fields) > 0 {
+		d.Extra = fields
+	}
+	return nil
+}
+
+func (d PolicyDefinition) MarshalJSON() ([]byte, error) {
+	type plain PolicyDefinition
+	return marshalWithExtra(plain(d), d.Extra)
+}
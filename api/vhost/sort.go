@@ -0,0 +1,74 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package vhost
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Sort sorts all collections in the definition by stable keys,
+// so that exports of the same definitions are always identical
+func (d *Definition) Sort() {
+	sort.SliceStable(d.Users, func(i, j int) bool { return d.Users[i].Name < d.Users[j].Name })
+	sort.SliceStable(d.VHosts, func(i, j int) bool { return d.VHosts[i].Name < d.VHosts[j].Name })
+	sort.SliceStable(d.Permissions, func(i, j int) bool {
+		a, b := d.Permissions[i], d.Permissions[j]
+		return less(a.VHost, b.VHost, a.User, b.User)
+	})
+	sort.SliceStable(d.TopicPermissions, func(i, j int) bool {
+		a, b := d.TopicPermissions[i], d.TopicPermissions[j]
+		return less(a.VHost, b.VHost, a.User, b.User, a.Exchange, b.Exchange)
+	})
+	sort.SliceStable(d.Parameters, func(i, j int) bool {
+		a, b := d.Parameters[i], d.Parameters[j]
+		return less(a.Component, b.Component, a.VHost, b.VHost, a.Name, b.Name)
+	})
+	sort.SliceStable(d.GlobalParameters, func(i, j int) bool { return d.GlobalParameters[i].Name < d.GlobalParameters[j].Name })
+	sort.SliceStable(d.Policies, func(i, j int) bool {
+		a, b := d.Policies[i], d.Policies[j]
+		return less(a.VHost, b.VHost, a.Name, b.Name)
+	})
+	sort.SliceStable(d.Queues, func(i, j int) bool {
+		a, b := d.Queues[i], d.Queues[j]
+		return less(a.VHost, b.VHost, a.Name, b.Name)
+	})
+	sort.SliceStable(d.Exchanges, func(i, j int) bool {
+		a, b := d.Exchanges[i], d.Exchanges[j]
+		return less(a.VHost, b.VHost, a.Name, b.Name)
+	})
+	sort.SliceStable(d.Bindings, func(i, j int) bool {
+		a, b := d.Bindings[i], d.Bindings[j]
+		return less(a.VHost, b.VHost, a.Source, b.Source, a.DestinationType, b.DestinationType,
+			a.Destination, b.Destination, a.RoutingKey, b.RoutingKey, jsonString(a.Arguments), jsonString(b.Arguments))
+	})
+}
+
+// less compares pairs of keys in order, the first pair that differs decides
+func less(pairs ...string) bool {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i] != pairs[i+1] {
+			return pairs[i] < pairs[i+1]
+		}
+	}
+	return false
+}
+
+// jsonString returns the value as JSON, maps are marshalled with sorted keys
+func jsonString(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
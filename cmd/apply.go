@@ -0,0 +1,592 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/LogiqsAgro/rmq/api/vhost"
+	"github.com/spf13/cobra"
+)
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Applies the desired state of the vhost from a JSON or YAML --file",
+	Long: `Applies the desired state of the vhost from a JSON or YAML --file, in the definitions format.
+
+The permissions, policies, queues, exchanges and bindings in the file are compared with the live
+state of the vhost, and a plan with the objects to create, update and delete is printed.
+After confirmation, or with --yes, the plan is executed. Objects in the file with another vhost
+are ignored, the vhost is created when it does not exist, and it is listed in the vhosts of the file.
+
+Objects that are not in the file are only deleted with --prune. The built-in amq.* exchanges
+and queues are never deleted.
+
+Queues and exchanges whose properties or arguments changed cannot be updated in place, they are
+marked as 'requires recreate' and are not changed, unless --recreate is used. Recreating a queue
+deletes its messages. Recreating a queue or exchange also deletes its bindings, the bindings that are
+not in the file are re-created afterwards, or deleted with --prune.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(applyFile) == 0 {
+			return fmt.Errorf("--file ( or -f ) is a required parameter")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		vhostName := api.Config.VHost
+		desired, _, err := readDefinitionFile(applyFile)
+		if err != nil {
+			return err
+		}
+		desired = definitionForVhost(desired, vhostName)
+
+		live, exists, err := getVhostState(vhostName)
+		if err != nil {
+			return err
+		}
+
+		problems, warnings := validateDefinition(desired, vhostName)
+		for _, w := range warnings {
+			fmt.Println("warning: " + w)
+		}
+		problems = append(problems, validateBindingReferences(desired, live, vhostName)...)
+		if len(problems) > 0 {
+			for _, p := range problems {
+				fmt.Println(p)
+			}
+			return fmt.Errorf("the definitions in '%s' are invalid, %d problem(s) found", applyFile, len(problems))
+		}
+
+		plan := []*planChange{}
+		if !exists {
+			if !containsVHost(desired.VHosts, vhostName) {
+				return fmt.Errorf("vhost '%s' does not exist, add it to the vhosts in '%s' to create it", vhostName, applyFile)
+			}
+			plan = append(plan, &planChange{action: planCreate, kind: "vhost", name: vhostName, run: func() error {
+				_, err := execute(api.PutVhost(vhostName).Body(map[string]interface{}{}), nil)
+				return err
+			}})
+		}
+		plan = append(plan, planApply(desired, live, vhostName, applyPrune, applyRecreate)...)
+
+		if len(plan) == 0 {
+			fmt.Printf("vhost '%s' is up to date, no changes\n", vhostName)
+			return nil
+		}
+		printPlan(plan)
+
+		if applyDryRun {
+			return nil
+		}
+		if !applyYes {
+			ok, err := confirm(fmt.Sprintf("Apply these changes to vhost '%s'?", vhostName))
+			if err != nil || !ok {
+				return err
+			}
+		}
+		return executePlan(plan)
+	},
+}
+
+const (
+	planCreate   = "create"
+	planUpdate   = "update"
+	planDelete   = "delete"
+	planRecreate = "recreate"
+)
+
+// planChange is a change of an object in the vhost
+type planChange struct {
+	action  string
+	kind    string
+	name    string
+	details []string
+	run     func() error
+}
+
+// definitionForVhost returns the definitions of the objects in the vhost,
+// objects without a vhost are in the vhost
+func definitionForVhost(d *vhost.Definition, vhostName string) *vhost.Definition {
+	in := func(objectVHost string) bool { return len(objectVHost) == 0 || objectVHost == vhostName }
+	result := &vhost.Definition{VHosts: d.VHosts}
+	for _, p := range d.Permissions {
+		if in(p.VHost) {
+			p.VHost = vhostName
+			result.Permissions = append(result.Permissions, p)
+		}
+	}
+	for _, p := range d.Policies {
+		if in(p.VHost) {
+			p.VHost = vhostName
+			result.Policies = append(result.Policies, p)
+		}
+	}
+	for _, q := range d.Queues {
+		if in(q.VHost) {
+			q.VHost = vhostName
+			result.Queues = append(result.Queues, q)
+		}
+	}
+	for _, e := range d.Exchanges {
+		if in(e.VHost) && !isBuiltinExchange(e.Name) {
+			e.VHost = vhostName
+			result.Exchanges = append(result.Exchanges, e)
+		}
+	}
+	for _, b := range d.Bindings {
+		if in(b.VHost) {
+			b.VHost = vhostName
+			result.Bindings = append(result.Bindings, b)
+		}
+	}
+	return result
+}
+
+// getVhostState returns the live definitions and permissions of the vhost, and whether the vhost exists
+func getVhostState(vhostName string) (*vhost.Definition, bool, error) {
+	live, err := getDefinition(vhostName)
+	if err != nil {
+		if api.IsStatus(err, http.StatusNotFound) {
+			return &vhost.Definition{}, false, nil
+		}
+		return nil, false, err
+	}
+	if err := getVhostPermissions(vhostName, live); err != nil {
+		return nil, false, err
+	}
+	live = definitionForVhost(live, vhostName)
+	return live, true, nil
+}
+
+// getVhostPermissions adds the permissions and topic permissions of the vhost to the definitions,
+// the definitions of a vhost do not have them
+func getVhostPermissions(vhostName string, d *vhost.Definition) error {
+	if _, err := execute(api.GetVhostPermissions(vhostName), &d.Permissions); err != nil {
+		return err
+	}
+	_, err := execute(api.GetVhostTopicPermissions(vhostName), &d.TopicPermissions)
+	return err
+}
+
+func containsVHost(vhosts []*vhost.VHost, name string) bool {
+	for _, v := range vhosts {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// planApply returns the changes to go from the live to the desired definitions of the vhost,
+// in the order in which they must be executed. Objects that are not desired are only deleted with prune,
+// and queues and exchanges that cannot be changed in place are only recreated with recreateChanged
+func planApply(desired, live *vhost.Definition, vhostName string, prune, recreateChanged bool) []*planChange {
+	creates, deletes := []*planChange{}, []*planChange{}
+
+	livePermissions := map[string]*vhost.Permission{}
+	for _, p := range live.Permissions {
+		livePermissions[p.Key()] = p
+	}
+	desiredPermissions := map[string]bool{}
+	for _, p := range desired.Permissions {
+		p := p
+		desiredPermissions[p.Key()] = true
+		change := &planChange{kind: "permissions", name: p.User, run: func() error { return putPermission(p) }}
+		if l, ok := livePermissions[p.Key()]; !ok {
+			change.action = planCreate
+		} else {
+			change.details = differences(nil, "configure", l.Configure, p.Configure)
+			change.details = differences(change.details, "write", l.Write, p.Write)
+			change.details = differences(change.details, "read", l.Read, p.Read)
+			change.action = planUpdate
+		}
+		creates = appendChange(creates, change)
+	}
+	for _, l := range live.Permissions {
+		l := l
+		if !desiredPermissions[l.Key()] && prune {
+			deletes = append(deletes, &planChange{action: planDelete, kind: "permissions", name: l.User, run: func() error {
+				_, err := execute(api.DeletePermissionsForVhostAndUser(l.VHost, l.User), nil)
+				return err
+			}})
+		}
+	}
+
+	recreated := map[string]bool{}
+	liveExchanges := map[string]*vhost.Exchange{}
+	for _, e := range live.Exchanges {
+		liveExchanges[e.Key()] = e
+	}
+	desiredExchanges := map[string]bool{}
+	for _, e := range desired.Exchanges {
+		e := e
+		desiredExchanges[e.Key()] = true
+		change := &planChange{action: planCreate, kind: "exchange", name: e.Name, run: func() error { return putExchange(e) }}
+		if l, ok := liveExchanges[e.Key()]; ok {
+			change.details = differences(nil, "type", l.Type, e.Type)
+			change.details = differences(change.details, "durable", l.Durable, e.Durable)
+			change.details = differences(change.details, "auto_delete", l.AutoDelete, e.AutoDelete)
+			change.details = differences(change.details, "internal", l.Internal, e.Internal)
+			change.details = argumentDifferences(change.details, l.Arguments, e.Arguments)
+			change.action = planRecreate
+			change.run = func() error {
+				return recreate(api.DeleteExchangeForVhost(e.VHost, e.Name), func() error { return putExchange(e) })
+			}
+			if len(change.details) > 0 && recreateChanged {
+				recreated["exchange "+e.Name] = true
+			}
+		}
+		creates = appendChange(creates, change)
+	}
+
+	liveQueues := map[string]*vhost.Queue{}
+	for _, q := range live.Queues {
+		liveQueues[q.Key()] = q
+	}
+	desiredQueues := map[string]bool{}
+	for _, q := range desired.Queues {
+		q := q
+		desiredQueues[q.Key()] = true
+		change := &planChange{action: planCreate, kind: "queue", name: q.Name, run: func() error { return putQueue(q) }}
+		if l, ok := liveQueues[q.Key()]; ok {
+			change.details = differences(nil, "durable", l.Durable, q.Durable)
+			change.details = differences(change.details, "auto_delete", l.AutoDelete, q.AutoDelete)
+			// the queue type is either the type, or the x-queue-type argument, so it is compared separately
+			change.details = differences(change.details, "type", queueType(l), queueType(q))
+			change.details = argumentDifferences(change.details, queueArguments(l), queueArguments(q))
+			change.action = planRecreate
+			change.run = func() error {
+				return recreate(api.DeleteQueueForVhost(q.VHost, q.Name), func() error { return putQueue(q) })
+			}
+			if len(change.details) > 0 && recreateChanged {
+				recreated["queue "+q.Name] = true
+			}
+		}
+		creates = appendChange(creates, change)
+	}
+
+	livePolicies := map[string]*vhost.Policy{}
+	for _, p := range live.Policies {
+		livePolicies[p.Key()] = p
+	}
+	desiredPolicies := map[string]bool{}
+	for _, p := range desired.Policies {
+		p := p
+		desiredPolicies[p.Key()] = true
+		change := &planChange{action: planCreate, kind: "policy", name: p.Name, run: func() error { return putPolicy(p) }}
+		if l, ok := livePolicies[p.Key()]; ok {
+			change.details = differences(nil, "pattern", l.Pattern, p.Pattern)
+			change.details = differences(change.details, "apply-to", policyApplyToOrDefault(l.ApplyTo), policyApplyToOrDefault(p.ApplyTo))
+			change.details = differences(change.details, "priority", l.Priority, p.Priority)
+			change.details = differences(change.details, "definition", l.Definition, p.Definition)
+			change.action = planUpdate
+		}
+		creates = appendChange(creates, change)
+	}
+
+	// bindings cannot change, a binding with another routing key or arguments is another binding
+	liveBindings := map[string]*vhost.Binding{}
+	for _, b := range live.Bindings {
+		liveBindings[b.Key()] = b
+	}
+	desiredBindings := map[string]bool{}
+	for _, b := range desired.Bindings {
+		b := b
+		desiredBindings[b.Key()] = true
+		_, exists := liveBindings[b.Key()]
+		if exists && len(recreatedBy(recreated, b)) == 0 {
+			continue
+		}
+		creates = append(creates, createBindingChange(b))
+	}
+	for _, l := range live.Bindings {
+		l := l
+		if desiredBindings[l.Key()] {
+			continue
+		}
+		// the bindings of a recreated exchange or queue are deleted with it, so they are
+		// re-created, unless they are pruned
+		if object := recreatedBy(recreated, l); len(object) > 0 {
+			if prune {
+				deletes = append(deletes, &planChange{action: planDelete, kind: "binding", name: l.Name(),
+					details: []string{"deleted with the recreated " + object}, run: func() error { return deleteMatchingBinding(l) }})
+			} else {
+				change := createBindingChange(l)
+				change.details = []string{"not in the file, re-created after the recreated " + object}
+				creates = append(creates, change)
+			}
+			continue
+		}
+		if prune {
+			deletes = append(deletes, &planChange{action: planDelete, kind: "binding", name: l.Name(), run: func() error { return deleteMatchingBinding(l) }})
+		}
+	}
+
+	if !prune {
+		return append(creates, deletes...)
+	}
+
+	for _, q := range live.Queues {
+		q := q
+		if !desiredQueues[q.Key()] && !strings.HasPrefix(q.Name, "amq.") {
+			deletes = append(deletes, &planChange{action: planDelete, kind: "queue", name: q.Name, run: func() error {
+				_, err := execute(api.DeleteQueueForVhost(q.VHost, q.Name), nil)
+				return err
+			}})
+		}
+	}
+	for _, e := range live.Exchanges {
+		e := e
+		if !desiredExchanges[e.Key()] {
+			deletes = append(deletes, &planChange{action: planDelete, kind: "exchange", name: e.Name, run: func() error {
+				_, err := execute(api.DeleteExchangeForVhost(e.VHost, e.Name), nil)
+				return err
+			}})
+		}
+	}
+	for _, p := range live.Policies {
+		p := p
+		if !desiredPolicies[p.Key()] {
+			deletes = append(deletes, &planChange{action: planDelete, kind: "policy", name: p.Name, run: func() error {
+				_, err := execute(api.DeletePolicyForVhost(p.VHost, p.Name), nil)
+				return err
+			}})
+		}
+	}
+	return append(creates, deletes...)
+}
+
+// recreatedBy returns the recreated exchange or queue the binding is deleted with, or an empty string
+func recreatedBy(recreated map[string]bool, b *vhost.Binding) string {
+	if recreated["exchange "+b.Source] {
+		return "exchange '" + b.Source + "'"
+	}
+	if recreated[b.DestinationType+" "+b.Destination] {
+		return b.DestinationType + " '" + b.Destination + "'"
+	}
+	return ""
+}
+
+func createBindingChange(b *vhost.Binding) *planChange {
+	return &planChange{action: planCreate, kind: "binding", name: b.Name(), run: func() error {
+		return postBinding(b.VHost, b.Source, b.Destination, b.DestinationType, b.RoutingKey, argumentsOrEmpty(b.Arguments))
+	}}
+}
+
+// appendChange appends the change, unless it is an update or recreate without differences
+func appendChange(changes []*planChange, change *planChange) []*planChange {
+	if change.action != planCreate && len(change.details) == 0 {
+		return changes
+	}
+	return append(changes, change)
+}
+
+// differences appends a description of the difference when the live and desired values differ
+func differences(details []string, name string, live, desired interface{}) []string {
+	if reflect.DeepEqual(normalizeJson(live), normalizeJson(desired)) {
+		return details
+	}
+	return append(details, fmt.Sprintf("%s: %s => %s", name, planValue(live), planValue(desired)))
+}
+
+// argumentDifferences appends a description of the difference when the live and desired arguments differ
+func argumentDifferences(details []string, live, desired map[string]interface{}) []string {
+	if argumentsEqual(live, desired) {
+		return details
+	}
+	return append(details, fmt.Sprintf("arguments: %s => %s", formatArguments(live), formatArguments(desired)))
+}
+
+// queueArguments returns the arguments of the queue without the x-queue-type argument
+func queueArguments(q *vhost.Queue) map[string]interface{} {
+	arguments := map[string]interface{}{}
+	for name, value := range q.Arguments {
+		if name != "x-queue-type" {
+			arguments[name] = value
+		}
+	}
+	return arguments
+}
+
+// queueType returns the type of the queue, from its type or x-queue-type argument, defaults to classic
+func queueType(q *vhost.Queue) string {
+	if len(q.Type) > 0 {
+		return q.Type
+	}
+	if t, ok := q.Arguments["x-queue-type"].(string); ok && len(t) > 0 {
+		return t
+	}
+	return "classic"
+}
+
+func argumentsOrEmpty(arguments map[string]interface{}) map[string]interface{} {
+	if arguments == nil {
+		return map[string]interface{}{}
+	}
+	return arguments
+}
+
+func planValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func policyApplyToOrDefault(applyTo string) string {
+	if len(applyTo) == 0 {
+		return "all"
+	}
+	return applyTo
+}
+
+// printPlan prints the changes and a summary
+func printPlan(plan []*planChange) {
+	symbols := map[string]string{planCreate: "+", planUpdate: "~", planDelete: "-", planRecreate: "-/+"}
+	counts := map[string]int{}
+	for _, c := range plan {
+		counts[c.action]++
+		suffix := ""
+		if c.action == planRecreate {
+			suffix = " (requires recreate)"
+		}
+		fmt.Printf("%3s %s '%s'%s\n", symbols[c.action], c.kind, c.name, suffix)
+		for _, d := range c.details {
+			fmt.Printf("        %s\n", d)
+		}
+	}
+	fmt.Printf("Plan: %d to create, %d to update, %d to delete, %d require recreate.\n",
+		counts[planCreate], counts[planUpdate], counts[planDelete], counts[planRecreate])
+	if counts[planRecreate] > 0 && !applyRecreate {
+		fmt.Println("Objects that require recreate are not changed, use --recreate to delete and recreate them.")
+	}
+}
+
+// executePlan executes the changes in order, and stops at the first failure
+func executePlan(plan []*planChange) error {
+	skipped := 0
+	for _, c := range plan {
+		if c.action == planRecreate && !applyRecreate {
+			skipped++
+			continue
+		}
+		if err := c.run(); err != nil {
+			return fmt.Errorf("failed to %s %s '%s': %s", c.action, c.kind, c.name, errorReason(err))
+		}
+		fmt.Printf("%sd %s '%s'\n", c.action, c.kind, c.name)
+	}
+	if skipped > 0 {
+		return fmt.Errorf("%d object(s) require recreate and were not changed", skipped)
+	}
+	return nil
+}
+
+func putPermission(p *vhost.Permission) error {
+	req := api.PutPermissionsForVhostAndUser(p.VHost, p.User).Body(map[string]interface{}{
+		"configure": p.Configure,
+		"write":     p.Write,
+		"read":      p.Read,
+	})
+	_, err := execute(req, nil)
+	return err
+}
+
+func putExchange(e *vhost.Exchange) error {
+	req := api.PutExchangeForVhost(e.VHost, e.Name).Body(map[string]interface{}{
+		"type":        e.Type,
+		"durable":     e.Durable,
+		"auto_delete": e.AutoDelete,
+		"internal":    e.Internal,
+		"arguments":   argumentsOrEmpty(e.Arguments),
+	})
+	_, err := execute(req, nil)
+	return explainDeclareError(err)
+}
+
+func putQueue(q *vhost.Queue) error {
+	arguments := queueArguments(q)
+	if queueType := queueType(q); queueType != "classic" || q.Arguments["x-queue-type"] != nil {
+		arguments["x-queue-type"] = queueType
+	}
+	req := api.PutQueueForVhost(q.VHost, q.Name).Body(map[string]interface{}{
+		"durable":     q.Durable,
+		"auto_delete": q.AutoDelete,
+		"arguments":   arguments,
+	})
+	_, err := execute(req, nil)
+	return explainDeclareError(err)
+}
+
+func putPolicy(p *vhost.Policy) error {
+	req := api.PutPolicyForVhost(p.VHost, p.Name).Body(map[string]interface{}{
+		"pattern":    p.Pattern,
+		"apply-to":   policyApplyToOrDefault(p.ApplyTo),
+		"priority":   p.Priority,
+		"definition": p.Definition,
+	})
+	_, err := execute(req, nil)
+	return err
+}
+
+// recreate deletes the object, and declares it again
+func recreate(deleteRequest api.Builder, declare func() error) error {
+	if _, err := execute(deleteRequest, nil); err != nil && !api.IsStatus(err, http.StatusNotFound) {
+		return err
+	}
+	return declare()
+}
+
+// deleteMatchingBinding deletes the binding between the source and destination with the same routing key and arguments,
+// the properties key that identifies the binding is not part of the definitions
+func deleteMatchingBinding(b *vhost.Binding) error {
+	bindings, err := getBindings(b.VHost, b.Source, b.Destination, b.DestinationType)
+	if err != nil {
+		return err
+	}
+	for _, live := range bindings {
+		if live.RoutingKey == b.RoutingKey && argumentsEqual(live.Arguments, b.Arguments) {
+			return deleteBinding(live)
+		}
+	}
+	return nil
+}
+
+var (
+	applyFile     string
+	applyPrune    bool
+	applyRecreate bool
+	applyDryRun   bool
+	applyYes      bool
+)
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	flags := applyCmd.PersistentFlags()
+	flags.StringVarP(&applyFile, "file", "f", "", "The JSON or YAML file with the desired definitions")
+	flags.BoolVar(&applyPrune, "prune", false, "Delete the objects in the vhost that are not in the file")
+	flags.BoolVar(&applyRecreate, "recreate", false, "Delete and recreate the queues and exchanges that cannot be changed in place")
+	flags.BoolVar(&applyDryRun, "dry-run", false, "Only print the plan")
+	flags.BoolVarP(&applyYes, "yes", "y", false, "Apply the plan without asking for confirmation")
+}
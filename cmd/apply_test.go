@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/LogiqsAgro/rmq/api/vhost"
+)
+
+func Test_planApply(t *testing.T) {
+	live := &vhost.Definition{
+		Queues: []*vhost.Queue{
+			{VHost: "/", Name: "orders", Durable: true, Arguments: map[string]interface{}{"x-queue-type": "classic"}},
+			{VHost: "/", Name: "same", Durable: true},
+			{VHost: "/", Name: "stale", Durable: true},
+			{VHost: "/", Name: "typed", Durable: true, Arguments: map[string]interface{}{"x-queue-type": "quorum"}},
+		},
+		Policies: []*vhost.Policy{
+			{VHost: "/", Name: "ttl", Pattern: ".*", Definition: vhost.NewPolicyDefinition(map[string]interface{}{"message-ttl": float64(1000)})},
+		},
+		Bindings: []*vhost.Binding{
+			{VHost: "/", Source: "amq.topic", Destination: "orders", DestinationType: "queue", RoutingKey: "#"},
+			{VHost: "/", Source: "amq.direct", Destination: "orders", DestinationType: "queue", RoutingKey: "orders"},
+		},
+	}
+	desired := &vhost.Definition{
+		Queues: []*vhost.Queue{
+			{VHost: "/", Name: "orders", Durable: true, Arguments: map[string]interface{}{"x-queue-type": "quorum"}},
+			{VHost: "/", Name: "same", Durable: true, Arguments: map[string]interface{}{}},
+			{VHost: "/", Name: "new", Durable: true},
+			{VHost: "/", Name: "typed", Durable: true, Type: "quorum"},
+		},
+		Policies: []*vhost.Policy{
+			{VHost: "/", Name: "ttl", Pattern: ".*", ApplyTo: "all", Definition: vhost.NewPolicyDefinition(map[string]interface{}{"message-ttl": 2000})},
+		},
+		Bindings: []*vhost.Binding{
+			{VHost: "/", Source: "amq.topic", Destination: "orders", DestinationType: "queue", RoutingKey: "#", Arguments: map[string]interface{}{}},
+		},
+	}
+
+	tests := []struct {
+		prune    bool
+		recreate bool
+		expected []string
+	}{
+		{false, false, []string{"recreate queue orders", "create queue new", "update policy ttl"}},
+		{true, false, []string{"recreate queue orders", "create queue new", "update policy ttl", "delete binding amq.direct -> queue orders (orders)", "delete queue stale"}},
+		{false, true, []string{"recreate queue orders", "create queue new", "update policy ttl", "create binding amq.topic -> queue orders (#)", "create binding amq.direct -> queue orders (orders)"}},
+		{true, true, []string{"recreate queue orders", "create queue new", "update policy ttl", "create binding amq.topic -> queue orders (#)", "delete binding amq.direct -> queue orders (orders)", "delete queue stale"}},
+	}
+
+	for _, test := range tests {
+		plan := planApply(desired, live, "/", test.prune, test.recreate)
+
+		actual := []string{}
+		for _, c := range plan {
+			actual = append(actual, c.action+" "+c.kind+" "+c.name)
+		}
+		if len(actual) != len(test.expected) {
+			t.Errorf("planApply(prune: %v, recreate: %v) = %v, expected %v", test.prune, test.recreate, actual, test.expected)
+			continue
+		}
+		for i := range actual {
+			if actual[i] != test.expected[i] {
+				t.Errorf("planApply(prune: %v, recreate: %v) = %v, expected %v", test.prune, test.recreate, actual, test.expected)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/LogiqsAgro/rmq/api"
+)
+
+// argumentTypes are the supported type suffixes for arguments in the key=value:type format
+var argumentTypes = []string{"string", "int", "float", "bool", "json"}
+
+// parseArguments parses a list of key=value:type arguments, see parseArgument
+func parseArguments(values []string) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	for _, value := range values {
+		key, val, err := parseArgument(value)
+		if err != nil {
+			return nil, err
+		}
+		args[key] = val
+	}
+	return args, nil
+}
+
+// parseArgument parses an argument in the key=value:type format.
+// The :type suffix is optional, and defaults to string, when the suffix
+// is not one of the argumentTypes it is considered part of the string value.
+func parseArgument(s string) (string, interface{}, error) {
+	i := strings.Index(s, "=")
+	if i < 1 {
+		return "", nil, fmt.Errorf("invalid argument '%s', use the key=value:type format, where type is one of %s", s, strings.Join(argumentTypes, ", "))
+	}
+
+	key, value, typ := s[:i], s[i+1:], "string"
+	if j := strings.LastIndex(value, ":"); j >= 0 {
+		for _, t := range argumentTypes {
+			if value[j+1:] == t {
+				value, typ = value[:j], t
+				break
+			}
+		}
+	}
+
+	val, err := convertArgument(value, typ)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid argument '%s': %v", s, err)
+	}
+	return key, val, nil
+}
+
+func convertArgument(value, typ string) (interface{}, error) {
+	switch typ {
+	case "int":
+		return strconv.ParseInt(value, 10, 64)
+	case "float":
+		return strconv.ParseFloat(value, 64)
+	case "bool":
+		return strconv.ParseBool(value)
+	case "json":
+		var v interface{}
+		err := json.Unmarshal([]byte(value), &v)
+		return v, err
+	default:
+		return value, nil
+	}
+}
+
+// argumentsEqual compares arguments by their json representation,
+// so numbers compare equal regardless of their go type, and nil equals empty
+func argumentsEqual(a, b map[string]interface{}) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	return reflect.DeepEqual(normalizeJson(a), normalizeJson(b))
+}
+
+// normalizeJson returns the value as it would be decoded from its json representation
+func normalizeJson(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return v
+	}
+	return normalized
+}
+
+// formatArguments formats the arguments as json
+func formatArguments(arguments map[string]interface{}) string {
+	if len(arguments) == 0 {
+		return "{}"
+	}
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		return fmt.Sprint(arguments)
+	}
+	return string(data)
+}
+
+var inequivalentArgRegexp = regexp.MustCompile(`inequivalent arg '([^']*)' for (queue|exchange) '(.*)' in vhost '(.*)': received (.*) but current is (.*)`)
+
+// explainDeclareError rewrites the RabbitMQ "inequivalent arg" error, that is returned
+// when an existing queue or exchange is declared with different arguments, into a readable message.
+// Any other error is returned unchanged.
+func explainDeclareError(err error) error {
+	e, ok := err.(*api.ResponseError)
+	if !ok {
+		return err
+	}
+
+	m := inequivalentArgRegexp.FindStringSubmatch(e.Reason)
+	if m == nil {
+		return err
+	}
+
+	return fmt.Errorf("%s '%s' in vhost '%s' already exists with a different definition\n"+
+		"  argument  : %s\n"+
+		"  requested : %s\n"+
+		"  current   : %s\n"+
+		"delete the %s first, or declare it with the same definition",
+		m[2], m[3], m[4], m[1], m[5], m[6], m[2])
+}
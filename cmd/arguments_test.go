@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LogiqsAgro/rmq/api"
+)
+
+func Test_parseArgument(t *testing.T) {
+	tests := []struct {
+		in    string
+		key   string
+		value interface{}
+	}{
+		{"x-max-length=10:int", "x-max-length", int64(10)},
+		{"x-ratio=0.5:float", "x-ratio", 0.5},
+		{"x-single-active-consumer=true:bool", "x-single-active-consumer", true},
+		{"x-dead-letter-exchange=dlx", "x-dead-letter-exchange", "dlx"},
+		{"x-dead-letter-exchange=dlx:string", "x-dead-letter-exchange", "dlx"},
+		{"x-uri=amqp://host:5672", "x-uri", "amqp://host:5672"},
+		{"x-empty=", "x-empty", ""},
+	}
+
+	for _, test := range tests {
+		key, value, err := parseArgument(test.in)
+		if err != nil {
+			t.Errorf("parseArgument(%q) returned error %v", test.in, err)
+			continue
+		}
+		if key != test.key || value != test.value {
+			t.Errorf("parseArgument(%q) = %q, %#v, expected %q, %#v", test.in, key, value, test.key, test.value)
+		}
+	}
+}
+
+func Test_parseArgument_Invalid(t *testing.T) {
+	for _, in := range []string{"", "=value", "x-max-length", "x-max-length=ten:int", "x-flag=yes:bool", "x-list=[1,:json"} {
+		if _, _, err := parseArgument(in); err == nil {
+			t.Errorf("parseArgument(%q) expected an error", in)
+		}
+	}
+}
+
+func Test_explainDeclareError(t *testing.T) {
+	err := explainDeclareError(&api.ResponseError{
+		StatusCode: 400,
+		Reason:     "inequivalent arg 'x-queue-type' for queue 'orders' in vhost '/': received the value 'quorum' of type 'longstr' but current is none",
+	})
+
+	msg := err.Error()
+	for _, expected := range []string{"queue 'orders' in vhost '/'", "x-queue-type", "the value 'quorum' of type 'longstr'", "current   : none"} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected '%s' in error message:\n%s", expected, msg)
+		}
+	}
+}
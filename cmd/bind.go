@@ -0,0 +1,212 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/LogiqsAgro/rmq/api/vhost"
+	"github.com/spf13/cobra"
+)
+
+// bindCmd represents the bind command
+var bindCmd = &cobra.Command{
+	Use:   "bind",
+	Short: "Binds a queue or exchange to a source exchange",
+	Long: `Binds the --destination queue, or exchange when --destination-type exchange is used, to the --source exchange.
+
+Binding arguments can be added with --arg key=value:type, where type is one of string (the default), int, float, bool or json.
+For headers exchanges, use --x-match and --header key=value:type to specify the headers to match.`,
+	PreRunE: validateBindingFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arguments, err := bindingArguments(cmd)
+		if err != nil {
+			return err
+		}
+
+		cmd.SilenceUsage = true
+
+		if err := postBinding(api.Config.VHost, bindSource, bindDestination, bindDestinationType, bindRoutingKey, arguments); err != nil {
+			return err
+		}
+
+		fmt.Printf("bound %s '%s' to exchange '%s' with routing key '%s' in vhost '%s'\n", bindDestinationType, bindDestination, bindSource, bindRoutingKey, api.Config.VHost)
+		return nil
+	},
+}
+
+// unbindCmd represents the unbind command
+var unbindCmd = &cobra.Command{
+	Use:   "unbind",
+	Short: "Removes the binding between a queue or exchange and a source exchange",
+	Long: `Removes the binding between the --destination queue, or exchange when --destination-type exchange is used, and the --source exchange.
+
+The binding to remove is found by matching the routing key and arguments against the existing bindings,
+so use the same --routing-key, --arg, --x-match and --header flags that were used to create the binding.`,
+	PreRunE: validateBindingFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arguments, err := bindingArguments(cmd)
+		if err != nil {
+			return err
+		}
+
+		cmd.SilenceUsage = true
+
+		bindings, err := getBindings(api.Config.VHost, bindSource, bindDestination, bindDestinationType)
+		if err != nil {
+			return err
+		}
+
+		matched := []*vhost.Binding{}
+		for _, b := range bindings {
+			if b.RoutingKey == bindRoutingKey && argumentsEqual(b.Arguments, arguments) {
+				matched = append(matched, b)
+			}
+		}
+
+		if len(matched) == 0 {
+			msg := fmt.Sprintf("no binding found from exchange '%s' to %s '%s' with routing key '%s' and arguments %s",
+				bindSource, bindDestinationType, bindDestination, bindRoutingKey, formatArguments(arguments))
+			for _, b := range bindings {
+				msg += fmt.Sprintf("\n  existing binding: routing key '%s', arguments %s", b.RoutingKey, formatArguments(b.Arguments))
+			}
+			return errors.New(msg)
+		}
+
+		for _, b := range matched {
+			if err := deleteBinding(b); err != nil {
+				return err
+			}
+			fmt.Printf("unbound %s '%s' from exchange '%s' with routing key '%s' in vhost '%s'\n", b.DestinationType, b.Destination, b.Source, b.RoutingKey, b.VHost)
+		}
+		return nil
+	},
+}
+
+var (
+	bindSource          string
+	bindDestination     string
+	bindDestinationType string
+	bindRoutingKey      string
+	bindArgs            []string
+	bindXMatch          string
+	bindHeaders         []string
+)
+
+var (
+	destinationTypes = []string{"queue", "exchange"}
+	xMatchValues     = []string{"all", "any", "all-with-x", "any-with-x"}
+)
+
+func validateBindingFlags(cmd *cobra.Command, args []string) error {
+	if len(bindSource) == 0 {
+		return fmt.Errorf("--source is a required parameter, the default exchange cannot be used as a binding source")
+	}
+	if len(bindDestination) == 0 {
+		return fmt.Errorf("--destination is a required parameter")
+	}
+	if !contains(destinationTypes, bindDestinationType) {
+		return fmt.Errorf("invalid destination type '%s', use one of %s", bindDestinationType, strings.Join(destinationTypes, ", "))
+	}
+	if cmd.Flags().Changed("x-match") && !contains(xMatchValues, bindXMatch) {
+		return fmt.Errorf("invalid x-match value '%s', use one of %s", bindXMatch, strings.Join(xMatchValues, ", "))
+	}
+	return nil
+}
+
+// bindingArguments collects the binding arguments from the --arg, --x-match and --header flags
+func bindingArguments(cmd *cobra.Command) (map[string]interface{}, error) {
+	arguments, err := parseArguments(bindArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := parseArguments(bindHeaders)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		arguments[k] = v
+	}
+
+	if cmd.Flags().Changed("x-match") {
+		arguments["x-match"] = bindXMatch
+	}
+	return arguments, nil
+}
+
+// postBinding binds the destination queue or exchange to the source exchange
+func postBinding(vhostName, source, destination, destinationType, routingKey string, arguments map[string]interface{}) error {
+	var req api.Builder
+	if destinationType == "exchange" {
+		req = api.PostBindingsEEForVhostAndSourceAndDestination(vhostName, source, destination)
+	} else {
+		req = api.PostBindingsEQForVhostAndExchangeAndQueue(vhostName, source, destination)
+	}
+	req.Body(map[string]interface{}{
+		"routing_key": routingKey,
+		"arguments":   arguments,
+	})
+	_, err := execute(req, nil)
+	return err
+}
+
+// getBindings lists the bindings between the source exchange and the destination queue or exchange
+func getBindings(vhostName, source, destination, destinationType string) ([]*vhost.Binding, error) {
+	var req api.Builder
+	if destinationType == "exchange" {
+		req = api.GetBindingsEEForVhostAndSourceAndDestination(vhostName, source, destination)
+	} else {
+		req = api.GetBindingsEQForVhostAndExchangeAndQueue(vhostName, source, destination)
+	}
+	bindings := []*vhost.Binding{}
+	_, err := execute(req, &bindings)
+	return bindings, err
+}
+
+// deleteBinding deletes the binding, the binding's properties key identifies
+// the binding between its source and destination
+func deleteBinding(b *vhost.Binding) error {
+	var req api.Builder
+	if b.DestinationType == "exchange" {
+		req = api.DeleteBindingsEEForVhostAndSourceAndDestinationAndProps(b.VHost, b.Source, b.Destination, b.PropertiesKey)
+	} else {
+		req = api.DeleteBindingsEQForVhostAndExchangeAndQueueAndProps(b.VHost, b.Source, b.Destination, b.PropertiesKey)
+	}
+	_, err := execute(req, nil)
+	return err
+}
+
+func addBindingFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.StringVar(&bindSource, "source", "", "The source exchange")
+	flags.StringVar(&bindDestination, "destination", "", "The destination queue or exchange")
+	flags.StringVar(&bindDestinationType, "destination-type", "queue", "The destination type: "+strings.Join(destinationTypes, ", "))
+	flags.StringVarP(&bindRoutingKey, "routing-key", "k", "", "The routing key")
+	flags.StringArrayVar(&bindArgs, "arg", []string{}, "Binding argument in the key=value:type format, type is one of "+strings.Join(argumentTypes, ", ")+", can be repeated")
+	flags.StringVar(&bindXMatch, "x-match", "", "How a headers exchange matches the --header values: "+strings.Join(xMatchValues, ", "))
+	flags.StringArrayVar(&bindHeaders, "header", []string{}, "Header to match for a headers exchange in the key=value:type format, can be repeated")
+}
+
+func init() {
+	rootCmd.AddCommand(bindCmd)
+	addBindingFlags(bindCmd)
+	rootCmd.AddCommand(unbindCmd)
+	addBindingFlags(unbindCmd)
+}
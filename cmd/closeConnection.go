@@ -0,0 +1,252 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// closeConnectionCmd represents the close connection command
+var closeConnectionCmd = &cobra.Command{
+	Use:   "connection",
+	Short: "Closes the connection with the given --name, or all connections matching the selectors",
+	Long: `Closes the connection with the given --name, or all connections matching the selectors.
+
+Connections can be selected by --username, --connection-vhost, --peer-host, the client provided
+--connection-name (a regular expression), --client-product, --client-version and --idle time.
+A connection is idle when all its channels are idle, a connection without channels is idle since
+it was opened. All given selectors must match.
+
+The matching connections are listed, and you are asked for confirmation before they are closed,
+unless --yes is used. The --reason is sent to the clients in the X-Reason header.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		selected := false
+		for _, name := range connectionSelectorFlags {
+			selected = selected || cmd.Flags().Changed(name)
+		}
+		if !selected {
+			return fmt.Errorf("specify --name or at least one of the selectors")
+		}
+		if len(closeConnectionName) > 0 {
+			if _, err := regexp.Compile(closeConnectionName); err != nil {
+				return fmt.Errorf("invalid --connection-name regular expression '%s': %v", closeConnectionName, err)
+			}
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		connections, err := selectConnections()
+		if err != nil {
+			return err
+		}
+		if len(connections) == 0 {
+			fmt.Println("no connections match the selectors")
+			return nil
+		}
+
+		printConnections(connections)
+
+		if !closeConnectionYes {
+			ok, err := confirm(fmt.Sprintf("Close %d connection(s)?", len(connections)))
+			if err != nil || !ok {
+				return err
+			}
+		}
+
+		failed := 0
+		for _, c := range connections {
+			req := api.DeleteConnection(c.Name)
+			if len(closeConnectionReason) > 0 {
+				req.Header("X-Reason", closeConnectionReason)
+			}
+			if _, err := execute(req, nil); err != nil {
+				failed++
+				fmt.Printf("failed to close connection '%s': %s\n", c.Name, errorReason(err))
+				continue
+			}
+			fmt.Printf("closed connection '%s'\n", c.Name)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d connection(s) could not be closed", failed, len(connections))
+		}
+		return nil
+	},
+}
+
+type (
+	// connectionInfo contains the connection details returned by the api
+	connectionInfo struct {
+		Name             string           `json:"name"`
+		User             string           `json:"user"`
+		VHost            string           `json:"vhost"`
+		PeerHost         string           `json:"peer_host"`
+		Channels         int              `json:"channels"`
+		ConnectedAt      int64            `json:"connected_at"`
+		ClientProperties clientProperties `json:"client_properties"`
+
+		// idle is the time since the last activity on the connection, zero when it is active
+		idle time.Duration
+	}
+
+	// clientProperties contains the properties the client sent when opening the connection
+	clientProperties struct {
+		ConnectionName string `json:"connection_name"`
+		Product        string `json:"product"`
+		Version        string `json:"version"`
+	}
+
+	// channelInfo contains the channel details returned by the api
+	channelInfo struct {
+		Name              string `json:"name"`
+		IdleSince         string `json:"idle_since"`
+		ConnectionDetails struct {
+			Name string `json:"name"`
+		} `json:"connection_details"`
+	}
+)
+
+// idleSinceLayout is the layout of the idle_since field of channels, in UTC
+const idleSinceLayout = "2006-01-02 15:04:05"
+
+var connectionSelectorFlags = []string{"name", "username", "connection-vhost", "peer-host", "connection-name", "client-product", "client-version", "idle"}
+
+// selectConnections returns the connections matching all selector flags
+func selectConnections() ([]*connectionInfo, error) {
+	connections := []*connectionInfo{}
+	if len(connectionName) > 0 {
+		c := &connectionInfo{}
+		if _, err := execute(api.GetConnection(connectionName), c); err != nil {
+			if api.IsStatus(err, http.StatusNotFound) {
+				return connections, nil
+			}
+			return nil, err
+		}
+		connections = append(connections, c)
+	} else if _, err := execute(api.GetConnections(), &connections); err != nil {
+		return nil, err
+	}
+
+	if closeConnectionIdle > 0 {
+		if err := setConnectionIdleTimes(connections, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	nameRegexp := regexp.MustCompile(closeConnectionName)
+	selected := []*connectionInfo{}
+	for _, c := range connections {
+		if matchesSelector(closeConnectionUsername, c.User) &&
+			matchesSelector(closeConnectionVHost, c.VHost) &&
+			matchesSelector(closeConnectionPeerHost, c.PeerHost) &&
+			matchesSelector(closeConnectionProduct, c.ClientProperties.Product) &&
+			matchesSelector(closeConnectionVersion, c.ClientProperties.Version) &&
+			(len(closeConnectionName) == 0 || nameRegexp.MatchString(c.ClientProperties.ConnectionName)) &&
+			(closeConnectionIdle == 0 || c.idle >= closeConnectionIdle) {
+			selected = append(selected, c)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Name < selected[j].Name })
+	return selected, nil
+}
+
+// matchesSelector returns true when the selector is not set, or equal to the value
+func matchesSelector(selector, value string) bool {
+	return len(selector) == 0 || selector == value
+}
+
+// setConnectionIdleTimes sets the idle time of the connections from the idle_since of their channels,
+// a connection is active when one of its channels is active
+func setConnectionIdleTimes(connections []*connectionInfo, now time.Time) error {
+	channels := []*channelInfo{}
+	if _, err := execute(api.GetChannels(), &channels); err != nil {
+		return err
+	}
+	byConnection := map[string][]*channelInfo{}
+	for _, ch := range channels {
+		byConnection[ch.ConnectionDetails.Name] = append(byConnection[ch.ConnectionDetails.Name], ch)
+	}
+
+	for _, c := range connections {
+		c.idle = now.Sub(time.UnixMilli(c.ConnectedAt))
+		for _, ch := range byConnection[c.Name] {
+			if len(ch.IdleSince) == 0 {
+				c.idle = 0
+				break
+			}
+			since, err := time.Parse(idleSinceLayout, ch.IdleSince)
+			if err != nil {
+				return fmt.Errorf("invalid idle_since '%s' of channel '%s': %v", ch.IdleSince, ch.Name, err)
+			}
+			if idle := now.Sub(since); idle < c.idle {
+				c.idle = idle
+			}
+		}
+	}
+	return nil
+}
+
+// printConnections prints a table with the details of the connections
+func printConnections(connections []*connectionInfo) {
+	rows := [][]string{}
+	for _, c := range connections {
+		idle := "-"
+		if c.idle > 0 {
+			idle = c.idle.Truncate(time.Second).String()
+		}
+		rows = append(rows, []string{
+			c.Name, c.User, c.VHost, c.PeerHost,
+			c.ClientProperties.ConnectionName, c.ClientProperties.Product, c.ClientProperties.Version, idle,
+		})
+	}
+	printTable([]string{"NAME", "USER", "VHOST", "PEER-HOST", "CONNECTION-NAME", "PRODUCT", "VERSION", "IDLE"}, rows)
+}
+
+var (
+	closeConnectionUsername string
+	closeConnectionVHost    string
+	closeConnectionPeerHost string
+	closeConnectionName     string
+	closeConnectionProduct  string
+	closeConnectionVersion  string
+	closeConnectionIdle     time.Duration
+	closeConnectionReason   string
+	closeConnectionYes      bool
+)
+
+func init() {
+	closeCmd.AddCommand(closeConnectionCmd)
+	flags := closeConnectionCmd.PersistentFlags()
+	flags.StringVarP(&connectionName, "name", "n", "", "The connection name")
+	flags.StringVarP(&closeConnectionUsername, "username", "u", "", "Select the connections of this user")
+	flags.StringVar(&closeConnectionVHost, "connection-vhost", "", "Select the connections to this vhost")
+	flags.StringVar(&closeConnectionPeerHost, "peer-host", "", "Select the connections from this peer host")
+	flags.StringVar(&closeConnectionName, "connection-name", "", "Select the connections whose client provided connection name matches this regular expression")
+	flags.StringVar(&closeConnectionProduct, "client-product", "", "Select the connections from this client product")
+	flags.StringVar(&closeConnectionVersion, "client-version", "", "Select the connections from this client version")
+	flags.DurationVar(&closeConnectionIdle, "idle", 0, "Select the connections that have been idle for at least this duration, e.g. 30m")
+	flags.StringVar(&closeConnectionReason, "reason", "", "The reason for closing the connections, sent to the clients")
+	flags.BoolVarP(&closeConnectionYes, "yes", "y", false, "Close without asking for confirmation")
+}
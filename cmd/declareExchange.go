@@ -0,0 +1,140 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// declareExchangeCmd represents the declare exchange command
+var declareExchangeCmd = &cobra.Command{
+	Use:   "exchange",
+	Short: "Declares an exchange in the vhost",
+	Long: `Declares an exchange in the vhost.
+
+Reports if the exchange was created, or if it already existed with the same definition.
+Declaring an existing exchange with a different definition fails, and reports the conflicting argument.
+
+Besides the built-in exchange types, the plugin exchange types ` + strings.Join(pluginExchangeTypes, ", ") + `
+are supported, other plugin exchange types must start with 'x-'.
+The x-delayed-message exchange type requires the --delayed-type flag.
+
+Arguments without a dedicated flag can be added with --arg key=value:type,
+where type is one of string (the default), int, float, bool or json.`,
+	PreRunE: validateDeclareExchange,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arguments, err := parseArguments(declareExchangeArgs)
+		if err != nil {
+			return err
+		}
+		if cmd.Flags().Changed("alternate-exchange") {
+			arguments["alternate-exchange"] = declareExchangeAlternateExchange
+		}
+		if cmd.Flags().Changed("delayed-type") {
+			arguments["x-delayed-type"] = declareExchangeDelayedType
+		}
+
+		cmd.SilenceUsage = true
+
+		req := api.PutExchangeForVhost(api.Config.VHost, declareExchangeName).Body(map[string]interface{}{
+			"type":        declareExchangeType,
+			"durable":     declareExchangeDurable,
+			"auto_delete": declareExchangeAutoDelete,
+			"internal":    declareExchangeInternal,
+			"arguments":   arguments,
+		})
+
+		resp, err := execute(req, nil)
+		if err != nil {
+			return explainDeclareError(err)
+		}
+
+		if resp.StatusCode == http.StatusCreated {
+			fmt.Printf("exchange '%s' created in vhost '%s'\n", declareExchangeName, api.Config.VHost)
+		} else {
+			fmt.Printf("exchange '%s' already exists in vhost '%s' with the same definition\n", declareExchangeName, api.Config.VHost)
+		}
+		return nil
+	},
+}
+
+var (
+	declareExchangeName              string
+	declareExchangeType              string
+	declareExchangeDurable           bool
+	declareExchangeAutoDelete        bool
+	declareExchangeInternal          bool
+	declareExchangeAlternateExchange string
+	declareExchangeDelayedType       string
+	declareExchangeArgs              []string
+)
+
+var (
+	exchangeTypes       = []string{"direct", "fanout", "topic", "headers"}
+	pluginExchangeTypes = []string{"x-consistent-hash", "x-delayed-message", "x-modulus-hash", "x-random", "x-recent-history", "x-local-random", "x-jms-topic"}
+)
+
+// isBuiltinExchange returns true for the default exchange and the amq.* exchanges, which cannot be declared or deleted
+func isBuiltinExchange(name string) bool {
+	return len(name) == 0 || strings.HasPrefix(name, "amq.")
+}
+
+// isExchangeType returns true for the built-in exchange types, and for plugin exchange types, which start with 'x-'
+func isExchangeType(name string) bool {
+	return contains(exchangeTypes, name) || strings.HasPrefix(name, "x-")
+}
+
+func validateDeclareExchange(cmd *cobra.Command, args []string) error {
+	if len(declareExchangeName) == 0 {
+		return fmt.Errorf("--name ( or -n ) is a required parameter")
+	}
+
+	if isBuiltinExchange(declareExchangeName) {
+		return fmt.Errorf("exchange names starting with 'amq.' are reserved for built-in exchanges")
+	}
+
+	if !isExchangeType(declareExchangeType) {
+		return fmt.Errorf("invalid exchange type '%s', use one of %s, %s or another plugin exchange type starting with 'x-'",
+			declareExchangeType, strings.Join(exchangeTypes, ", "), strings.Join(pluginExchangeTypes, ", "))
+	}
+
+	if declareExchangeType == "x-delayed-message" && !cmd.Flags().Changed("delayed-type") {
+		return fmt.Errorf("the x-delayed-message exchange type requires --delayed-type, e.g. --delayed-type direct")
+	}
+
+	if cmd.Flags().Changed("delayed-type") && declareExchangeType != "x-delayed-message" {
+		return fmt.Errorf("--delayed-type is only supported by the x-delayed-message exchange type")
+	}
+	return nil
+}
+
+func init() {
+	declareCmd.AddCommand(declareExchangeCmd)
+	flags := declareExchangeCmd.PersistentFlags()
+	flags.StringVarP(&declareExchangeName, "name", "n", "", "The name of the exchange to declare")
+	flags.StringVarP(&declareExchangeType, "type", "t", "direct", "The exchange type: "+strings.Join(exchangeTypes, ", ")+" or a plugin exchange type like "+strings.Join(pluginExchangeTypes, ", "))
+	flags.BoolVar(&declareExchangeDurable, "durable", true, "Declare a durable exchange, that survives a broker restart")
+	flags.BoolVar(&declareExchangeAutoDelete, "auto-delete", false, "Declare an exchange that is deleted when its last binding is removed")
+	flags.BoolVar(&declareExchangeInternal, "internal", false, "Declare an internal exchange, that clients cannot publish to directly")
+	flags.StringVar(&declareExchangeAlternateExchange, "alternate-exchange", "", "The exchange to route messages to that cannot be routed by this exchange (alternate-exchange)")
+	flags.StringVar(&declareExchangeDelayedType, "delayed-type", "", "The exchange type used for routing by an x-delayed-message exchange (x-delayed-type)")
+	flags.StringArrayVar(&declareExchangeArgs, "arg", []string{}, "Additional exchange argument in the key=value:type format, type is one of "+strings.Join(argumentTypes, ", ")+", can be repeated")
+}
@@ -0,0 +1,176 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// declareQueueCmd represents the declare queue command
+var declareQueueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Declares a queue in the vhost",
+	Long: `Declares a queue in the vhost.
+
+Reports if the queue was created, or if it already existed with the same definition.
+Declaring an existing queue with different arguments fails, and reports the conflicting argument.
+
+Arguments without a dedicated flag can be added with --arg key=value:type,
+where type is one of string (the default), int, float, bool or json.`,
+	PreRunE: validateDeclareQueue,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arguments, err := declareQueueArguments(cmd)
+		if err != nil {
+			return err
+		}
+
+		cmd.SilenceUsage = true
+
+		req := api.PutQueueForVhost(api.Config.VHost, declareQueueName).Body(map[string]interface{}{
+			"durable":     declareQueueDurable,
+			"auto_delete": declareQueueAutoDelete,
+			"arguments":   arguments,
+		})
+
+		resp, err := execute(req, nil)
+		if err != nil {
+			return explainDeclareError(err)
+		}
+
+		if resp.StatusCode == http.StatusCreated {
+			fmt.Printf("queue '%s' created in vhost '%s'\n", declareQueueName, api.Config.VHost)
+		} else {
+			fmt.Printf("queue '%s' already exists in vhost '%s' with the same definition\n", declareQueueName, api.Config.VHost)
+		}
+		return nil
+	},
+}
+
+var (
+	declareQueueName                 string
+	declareQueueDurable              bool
+	declareQueueAutoDelete           bool
+	declareQueueType                 string
+	declareQueueMaxLength            int64
+	declareQueueMaxLengthBytes       int64
+	declareQueueOverflow             string
+	declareQueueMessageTtl           int64
+	declareQueueExpires              int64
+	declareQueueDeadLetterExchange   string
+	declareQueueDeadLetterRoutingKey string
+	declareQueueSingleActiveConsumer bool
+	declareQueueDeliveryLimit        int64
+	declareQueueArgs                 []string
+)
+
+var (
+	queueTypes     = []string{"classic", "quorum", "stream"}
+	queueOverflows = []string{"drop-head", "reject-publish", "reject-publish-dlx"}
+)
+
+func validateDeclareQueue(cmd *cobra.Command, args []string) error {
+	if len(declareQueueName) == 0 {
+		return fmt.Errorf("--name ( or -n ) is a required parameter")
+	}
+
+	if !contains(queueTypes, declareQueueType) {
+		return fmt.Errorf("invalid queue type '%s', use one of %s", declareQueueType, strings.Join(queueTypes, ", "))
+	}
+
+	if cmd.Flags().Changed("overflow") && !contains(queueOverflows, declareQueueOverflow) {
+		return fmt.Errorf("invalid overflow behaviour '%s', use one of %s", declareQueueOverflow, strings.Join(queueOverflows, ", "))
+	}
+
+	if declareQueueType != "classic" {
+		if !declareQueueDurable || declareQueueAutoDelete {
+			return fmt.Errorf("%s queues must be durable, and cannot be auto-delete", declareQueueType)
+		}
+	}
+
+	if cmd.Flags().Changed("delivery-limit") && declareQueueType != "quorum" {
+		return fmt.Errorf("--delivery-limit is only supported by quorum queues")
+	}
+
+	if cmd.Flags().Changed("single-active-consumer") && declareQueueType == "stream" {
+		return fmt.Errorf("--single-active-consumer is not supported by stream queues")
+	}
+	return nil
+}
+
+// declareQueueArguments collects the x-arguments from the typed flags and the --arg flags
+func declareQueueArguments(cmd *cobra.Command) (map[string]interface{}, error) {
+	arguments, err := parseArguments(declareQueueArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("type") {
+		arguments["x-queue-type"] = declareQueueType
+	}
+	if flags.Changed("max-length") {
+		arguments["x-max-length"] = declareQueueMaxLength
+	}
+	if flags.Changed("max-length-bytes") {
+		arguments["x-max-length-bytes"] = declareQueueMaxLengthBytes
+	}
+	if flags.Changed("overflow") {
+		arguments["x-overflow"] = declareQueueOverflow
+	}
+	if flags.Changed("message-ttl") {
+		arguments["x-message-ttl"] = declareQueueMessageTtl
+	}
+	if flags.Changed("expires") {
+		arguments["x-expires"] = declareQueueExpires
+	}
+	if flags.Changed("dead-letter-exchange") {
+		arguments["x-dead-letter-exchange"] = declareQueueDeadLetterExchange
+	}
+	if flags.Changed("dead-letter-routing-key") {
+		arguments["x-dead-letter-routing-key"] = declareQueueDeadLetterRoutingKey
+	}
+	if flags.Changed("single-active-consumer") {
+		arguments["x-single-active-consumer"] = declareQueueSingleActiveConsumer
+	}
+	if flags.Changed("delivery-limit") {
+		arguments["x-delivery-limit"] = declareQueueDeliveryLimit
+	}
+	return arguments, nil
+}
+
+func init() {
+	declareCmd.AddCommand(declareQueueCmd)
+	flags := declareQueueCmd.PersistentFlags()
+	flags.StringVarP(&declareQueueName, "name", "n", "", "The name of the queue to declare")
+	flags.BoolVar(&declareQueueDurable, "durable", true, "Declare a durable queue, that survives a broker restart")
+	flags.BoolVar(&declareQueueAutoDelete, "auto-delete", false, "Declare a queue that is deleted when its last consumer unsubscribes")
+	flags.StringVarP(&declareQueueType, "type", "t", "classic", "The queue type: "+strings.Join(queueTypes, ", "))
+	flags.Int64Var(&declareQueueMaxLength, "max-length", 0, "The maximum number of ready messages in the queue (x-max-length)")
+	flags.Int64Var(&declareQueueMaxLengthBytes, "max-length-bytes", 0, "The maximum total size in bytes of the ready messages in the queue (x-max-length-bytes)")
+	flags.StringVar(&declareQueueOverflow, "overflow", "", "The behaviour when the queue is full (x-overflow): "+strings.Join(queueOverflows, ", "))
+	flags.Int64Var(&declareQueueMessageTtl, "message-ttl", 0, "The time in milliseconds a message can stay in the queue before it is discarded or dead-lettered (x-message-ttl)")
+	flags.Int64Var(&declareQueueExpires, "expires", 0, "The time in milliseconds a queue can be unused before it is deleted (x-expires)")
+	flags.StringVar(&declareQueueDeadLetterExchange, "dead-letter-exchange", "", "The exchange to dead-letter messages to (x-dead-letter-exchange)")
+	flags.StringVar(&declareQueueDeadLetterRoutingKey, "dead-letter-routing-key", "", "The routing key to use when dead-lettering messages (x-dead-letter-routing-key)")
+	flags.BoolVar(&declareQueueSingleActiveConsumer, "single-active-consumer", false, "Only deliver messages to one consumer at a time (x-single-active-consumer)")
+	flags.Int64Var(&declareQueueDeliveryLimit, "delivery-limit", 0, "The number of redeliveries before a message is dropped or dead-lettered, quorum queues only (x-delivery-limit)")
+	flags.StringArrayVar(&declareQueueArgs, "arg", []string{}, "Additional queue argument in the key=value:type format, type is one of "+strings.Join(argumentTypes, ", ")+", can be repeated")
+}
@@ -0,0 +1,290 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/LogiqsAgro/rmq/api/vhost"
+)
+
+// definitionObject identifies an object in the definitions
+type definitionObject struct {
+	Type  string
+	VHost string
+	Name  string
+}
+
+// readDefinitionFile reads the JSON or YAML definitions file, and returns both the
+// decoded definitions and the generic document, which contains all fields of the file
+func readDefinitionFile(path string) (*vhost.Definition, interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var document interface{}
+	if err := decodeJsonOrYaml(data, &document); err != nil {
+		return nil, nil, fmt.Errorf("invalid definitions file '%s', expected JSON or YAML: %v", path, err)
+	}
+	if _, ok := document.(map[string]interface{}); !ok {
+		return nil, nil, fmt.Errorf("invalid definitions file '%s', expected an object", path)
+	}
+
+	definition := &vhost.Definition{}
+	if err := decodeJsonOrYaml(data, definition); err != nil {
+		return nil, nil, fmt.Errorf("invalid definitions file '%s': %v", path, err)
+	}
+	return definition, document, nil
+}
+
+// getDefinition returns the live definitions of the vhost, or of all vhosts when vhostName is empty
+func getDefinition(vhostName string) (*vhost.Definition, error) {
+	req := api.GetDefinitions()
+	if len(vhostName) > 0 {
+		req = api.GetDefinitionsForVhost(vhostName)
+	}
+	definition := &vhost.Definition{}
+	if _, err := execute(req, definition); err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+// validateDefinition returns the structural problems in the definitions, and the warnings for what
+// cannot be checked, like policy definition keys that are not in the catalogue. The vhost of the objects
+// is taken from vhostName when it is set, otherwise every object must have a vhost
+func validateDefinition(d *vhost.Definition, vhostName string) (problems, warnings []string) {
+	problems, warnings = []string{}, []string{}
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	warning := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+	checkVHost := func(kind, name, objectVHost string) {
+		if len(vhostName) == 0 && len(objectVHost) == 0 {
+			problem("%s '%s' has no vhost", kind, name)
+		}
+	}
+
+	for i, u := range d.Users {
+		if len(u.Name) == 0 {
+			problem("user #%d has no name", i+1)
+		}
+		if len(u.HashingAlgorithm) > 0 && !isHashingAlgorithm(u.HashingAlgorithm) {
+			problem("user '%s' has an unknown hashing_algorithm '%s'", u.Name, u.HashingAlgorithm)
+		}
+		if len(u.Name) > 0 && len(u.PasswordHash) == 0 {
+			// exports made with --strip-passwords have no password hashes
+			warning("user '%s' has no password_hash, importing it may leave the user without a password", u.Name)
+		}
+	}
+	for i, v := range d.VHosts {
+		if len(v.Name) == 0 {
+			problem("vhost #%d has no name", i+1)
+		}
+	}
+	for _, p := range d.Permissions {
+		if len(p.User) == 0 || len(p.VHost) == 0 {
+			problem("permission of user '%s' in vhost '%s' must have a user and a vhost", p.User, p.VHost)
+		}
+		for name, re := range map[string]string{"configure": p.Configure, "write": p.Write, "read": p.Read} {
+			if _, err := regexp.Compile(re); err != nil {
+				// RabbitMQ evaluates the permissions as PCRE, which supports more than Go regular expressions
+				warning("permission of user '%s' in vhost '%s' has a %s regular expression '%s' that cannot be checked: %v", p.User, p.VHost, name, re, err)
+			}
+		}
+	}
+	for i, p := range d.Policies {
+		if len(p.Name) == 0 {
+			problem("policy #%d has no name", i+1)
+			continue
+		}
+		checkVHost("policy", p.Name, p.VHost)
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			// RabbitMQ evaluates the pattern as PCRE, which supports more than Go regular expressions
+			warning("policy '%s' has a pattern '%s' that cannot be checked: %v", p.Name, p.Pattern, err)
+		}
+		if len(p.ApplyTo) > 0 && !contains(policyApplyTo, p.ApplyTo) {
+			problem("policy '%s' has an unknown apply-to '%s'", p.Name, p.ApplyTo)
+		}
+		definition := p.Definition.Map()
+		if len(definition) == 0 {
+			problem("policy '%s' has an empty definition", p.Name)
+			continue
+		}
+		// keys of newer RabbitMQ versions and plugins are not in the catalogue, they are passed on as is
+		keys := []string{}
+		for key := range definition {
+			if findPolicyKey(key) == nil {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			sort.Strings(keys)
+			warning("policy '%s' has definition keys that are not in the catalogue: %s", p.Name, strings.Join(keys, ", "))
+		}
+		if err := validatePolicyDefinition(definition, false, true); err != nil {
+			problem("policy '%s': %v", p.Name, err)
+		}
+	}
+	for i, q := range d.Queues {
+		if len(q.Name) == 0 {
+			problem("queue #%d has no name", i+1)
+			continue
+		}
+		checkVHost("queue", q.Name, q.VHost)
+		if queueType, ok := q.Arguments["x-queue-type"].(string); ok && !contains(queueTypes, queueType) {
+			problem("queue '%s' has an unknown x-queue-type '%s'", q.Name, queueType)
+		}
+	}
+	for i, e := range d.Exchanges {
+		if len(e.Name) == 0 {
+			problem("exchange #%d has no name", i+1)
+			continue
+		}
+		checkVHost("exchange", e.Name, e.VHost)
+		if !isExchangeType(e.Type) {
+			problem("exchange '%s' has an unknown type '%s'", e.Name, e.Type)
+		}
+	}
+	for i, b := range d.Bindings {
+		if len(b.Source) == 0 || len(b.Destination) == 0 {
+			problem("binding #%d must have a source and a destination", i+1)
+			continue
+		}
+		checkVHost("binding", b.Name(), b.VHost)
+		if b.DestinationType != "queue" && b.DestinationType != "exchange" {
+			problem("binding %s has an unknown destination_type '%s'", b.Name(), b.DestinationType)
+		}
+	}
+	return problems, warnings
+}
+
+// validateBindingReferences returns a problem for each binding whose source or destination
+// is neither in the definitions, nor in the live definitions
+func validateBindingReferences(d, live *vhost.Definition, vhostName string) []string {
+	known := map[definitionObject]bool{}
+	for _, def := range []*vhost.Definition{d, live} {
+		for _, q := range def.Queues {
+			known[definitionObject{"queue", objectVHost(vhostName, q.VHost), q.Name}] = true
+		}
+		for _, e := range def.Exchanges {
+			known[definitionObject{"exchange", objectVHost(vhostName, e.VHost), e.Name}] = true
+		}
+	}
+
+	problems := []string{}
+	for _, b := range d.Bindings {
+		v := objectVHost(vhostName, b.VHost)
+		if !isBuiltinExchange(b.Source) && !known[definitionObject{"exchange", v, b.Source}] {
+			problems = append(problems, fmt.Sprintf("binding %s has an unknown source exchange '%s'", b.Name(), b.Source))
+		}
+		if !(b.DestinationType == "exchange" && isBuiltinExchange(b.Destination)) && !known[definitionObject{b.DestinationType, v, b.Destination}] {
+			problems = append(problems, fmt.Sprintf("binding %s has an unknown destination %s '%s'", b.Name(), b.DestinationType, b.Destination))
+		}
+	}
+	return problems
+}
+
+// newDefinitionObjects returns the users, vhosts, policies, queues, exchanges and bindings
+// in the definitions that do not exist in the live definitions
+func newDefinitionObjects(d, live *vhost.Definition, vhostName string) []*definitionObject {
+	existing := map[definitionObject]bool{}
+	for _, o := range definitionObjects(live, vhostName) {
+		existing[*o] = true
+	}
+
+	created := []*definitionObject{}
+	for _, o := range definitionObjects(d, vhostName) {
+		if !existing[*o] {
+			created = append(created, o)
+		}
+	}
+	return created
+}
+
+// definitionObjects returns the identities of the objects in the definitions
+func definitionObjects(d *vhost.Definition, vhostName string) []*definitionObject {
+	objects := []*definitionObject{}
+	add := func(kind, objectVhost, name string) {
+		objects = append(objects, &definitionObject{Type: kind, VHost: objectVhost, Name: name})
+	}
+	if len(vhostName) == 0 {
+		for _, u := range d.Users {
+			add("user", "", u.Name)
+		}
+		for _, v := range d.VHosts {
+			add("vhost", "", v.Name)
+		}
+	}
+	for _, p := range d.Policies {
+		add("policy", objectVHost(vhostName, p.VHost), p.Name)
+	}
+	for _, q := range d.Queues {
+		add("queue", objectVHost(vhostName, q.VHost), q.Name)
+	}
+	for _, e := range d.Exchanges {
+		add("exchange", objectVHost(vhostName, e.VHost), e.Name)
+	}
+	for _, b := range d.Bindings {
+		add("binding", objectVHost(vhostName, b.VHost), b.Name())
+	}
+	return objects
+}
+
+// printDefinitionObjects prints a table with the objects, sorted by type, vhost and name
+func printDefinitionObjects(objects []*definitionObject) {
+	sort.SliceStable(objects, func(i, j int) bool {
+		a, b := objects[i], objects[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.VHost != b.VHost {
+			return a.VHost < b.VHost
+		}
+		return a.Name < b.Name
+	})
+	rows := [][]string{}
+	for _, o := range objects {
+		rows = append(rows, []string{o.Type, o.VHost, o.Name})
+	}
+	printTable([]string{"TYPE", "VHOST", "NAME"}, rows)
+}
+
+// objectVHost returns the vhost of an object, vhostName overrides the vhost of the object when it is set
+func objectVHost(vhostName, objectVHost string) string {
+	if len(vhostName) > 0 {
+		return vhostName
+	}
+	return objectVHost
+}
+
+// isHashingAlgorithm returns true when the algorithm is a RabbitMQ password hashing algorithm
+func isHashingAlgorithm(algorithm string) bool {
+	for _, name := range hashingAlgorithms {
+		if name == algorithm {
+			return true
+		}
+	}
+	return algorithm == "rabbit_password_hashing_md5"
+}
@@ -0,0 +1,72 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// deleteExchangeCmd represents the delete exchange command
+var deleteExchangeCmd = &cobra.Command{
+	Use:   "exchange",
+	Short: "Deletes the exchange with the given --name",
+	Long: `Deletes the exchange with the given --name.
+
+The default exchange and the built-in amq.* exchanges cannot be deleted.
+Use --if-unused to only delete the exchange when it is not the source of any binding.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(deleteExchangeName) == 0 {
+			return fmt.Errorf("--name ( or -n ) is a required parameter")
+		}
+		if isBuiltinExchange(deleteExchangeName) {
+			return fmt.Errorf("refusing to delete the built-in exchange '%s'", deleteExchangeName)
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		req := api.DeleteExchangeForVhost(api.Config.VHost, deleteExchangeName).QueryParameters(func(query api.Query) {
+			query.AddIf(deleteExchangeIfUnused, "if-unused", "true")
+		})
+
+		if _, err := execute(req, nil); err != nil {
+			if api.IsStatus(err, http.StatusNotFound) {
+				return fmt.Errorf("exchange '%s' not found in vhost '%s'", deleteExchangeName, api.Config.VHost)
+			}
+			return fmt.Errorf("failed to delete exchange '%s': %s", deleteExchangeName, errorReason(err))
+		}
+
+		fmt.Printf("deleted exchange '%s' from vhost '%s'\n", deleteExchangeName, api.Config.VHost)
+		return nil
+	},
+}
+
+var (
+	deleteExchangeName     string
+	deleteExchangeIfUnused bool
+)
+
+func init() {
+	deleteCmd.AddCommand(deleteExchangeCmd)
+	flags := deleteExchangeCmd.PersistentFlags()
+	flags.StringVarP(&deleteExchangeName, "name", "n", "", "The name of the exchange to delete")
+	flags.BoolVar(&deleteExchangeIfUnused, "if-unused", false, "Only delete the exchange when it is not the source of any binding")
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// deleteQueueCmd represents the delete queue command
+var deleteQueueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Deletes the queue with the given --name, or all queues matching the --name regex when --regex is used",
+	Long: `Deletes the queue with the given --name, or all queues matching the --name regex when --regex is used.
+
+The matching queues are listed with their message and consumer counts,
+and you are asked for confirmation before they are deleted, unless --yes is used.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(api.Page.Name) == 0 {
+			return fmt.Errorf("--name ( or -n ) is a required parameter")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		vhost := api.Config.VHost
+		queues, err := selectQueues(vhost)
+		if err != nil {
+			return err
+		}
+		if len(queues) == 0 {
+			fmt.Printf("no queues in vhost '%s' match '%s'\n", vhost, api.Page.Name)
+			return nil
+		}
+
+		printQueueCounts(queues)
+
+		if deleteQueueDryRun {
+			fmt.Printf("dry run: %d queue(s) would be deleted from vhost '%s'\n", len(queues), vhost)
+			return nil
+		}
+
+		if !deleteQueueYes {
+			ok, err := confirm(fmt.Sprintf("Delete %d queue(s) from vhost '%s'?", len(queues), vhost))
+			if err != nil || !ok {
+				return err
+			}
+		}
+
+		failed := 0
+		for _, q := range queues {
+			req := api.DeleteQueueForVhost(vhost, q.Name).QueryParameters(func(query api.Query) {
+				query.AddIf(deleteQueueIfEmpty, "if-empty", "true")
+				query.AddIf(deleteQueueIfUnused, "if-unused", "true")
+			})
+			if _, err := execute(req, nil); err != nil {
+				failed++
+				fmt.Printf("failed to delete queue '%s': %s\n", q.Name, errorReason(err))
+				continue
+			}
+			fmt.Printf("deleted queue '%s'\n", q.Name)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d queue(s) could not be deleted", failed, len(queues))
+		}
+		return nil
+	},
+}
+
+var (
+	deleteQueueIfEmpty  bool
+	deleteQueueIfUnused bool
+	deleteQueueYes      bool
+	deleteQueueDryRun   bool
+)
+
+// printQueueCounts prints a table with the message and consumer counts of the queues
+func printQueueCounts(queues []*queueInfo) {
+	rows := [][]string{}
+	for _, q := range queues {
+		rows = append(rows, []string{q.Name, fmt.Sprint(q.Messages), fmt.Sprint(q.Consumers)})
+	}
+	printTable([]string{"NAME", "MESSAGES", "CONSUMERS"}, rows)
+}
+
+func init() {
+	deleteCmd.AddCommand(deleteQueueCmd)
+	api.AddPagingFlags(deleteQueueCmd)
+	flags := deleteQueueCmd.PersistentFlags()
+	flags.BoolVar(&deleteQueueIfEmpty, "if-empty", false, "Only delete queues that contain no messages")
+	flags.BoolVar(&deleteQueueIfUnused, "if-unused", false, "Only delete queues that have no consumers")
+	flags.BoolVarP(&deleteQueueYes, "yes", "y", false, "Delete without asking for confirmation")
+	flags.BoolVar(&deleteQueueDryRun, "dry-run", false, "Only print the queues that would be deleted")
+}
@@ -0,0 +1,276 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/LogiqsAgro/rmq/api/vhost"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <source> <target>",
+	Short: "Compares the definitions of two brokers, vhosts or definitions files",
+	Long: `Compares the definitions of two brokers, vhosts or definitions files, and reports the added,
+removed and changed users, vhosts, permissions, parameters, policies, queues, exchanges and bindings.
+
+Each side is either a JSON or YAML definitions file, or a config profile with an optional vhost,
+in the 'profile' or 'profile:vhost' format. Profiles are configured in the config file:
+
+  profiles:
+    staging:
+      host: rabbitmq.staging
+      user: admin
+      password: secret
+
+A profile must set the host, user and password, the scheme and api-port that are not set in the
+profile are taken from the current settings. The profile 'current' uses the current settings. When a side is a vhost,
+the vhosts of the objects are ignored, so vhosts with different names can be compared.
+
+The --format is human, json or unified. The command exits with exit code 1 when there are differences.`,
+	Args: cobra.ExactArgs(2),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !contains(diffFormats, diffFormat) {
+			return fmt.Errorf("invalid --format '%s', expected one of %s", diffFormat, strings.Join(diffFormats, ", "))
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		source, sourceVhost, err := readDiffSide(args[0])
+		if err != nil {
+			return err
+		}
+		target, targetVhost, err := readDiffSide(args[1])
+		if err != nil {
+			return err
+		}
+		if sourceVhost || targetVhost {
+			stripVHosts(source)
+			stripVHosts(target)
+		}
+		source.Sort()
+		target.Sort()
+
+		changes := vhost.Compare(source, target)
+		switch diffFormat {
+		case "json":
+			if err := printIndentedJson(changes); err != nil {
+				return err
+			}
+		case "unified":
+			printUnifiedDiff(args[0], args[1], changes)
+		default:
+			printChanges(changes)
+		}
+
+		if len(changes) > 0 {
+			return fmt.Errorf("%d difference(s) between '%s' and '%s'", len(changes), args[0], args[1])
+		}
+		return nil
+	},
+}
+
+var diffFormats = []string{"human", "json", "unified"}
+
+// readDiffSide reads the definitions of a file, or of a profile with an optional vhost,
+// and returns whether the definitions are of a single vhost
+func readDiffSide(side string) (*vhost.Definition, bool, error) {
+	if _, err := os.Stat(side); err == nil {
+		definition, _, err := readDefinitionFile(side)
+		return definition, false, err
+	}
+
+	profile, vhostName := side, ""
+	if i := strings.Index(side, ":"); i >= 0 {
+		profile, vhostName = side[:i], side[i+1:]
+	}
+
+	saved := *api.Config
+	defer func() { *api.Config = saved }()
+	if profile != "current" {
+		settings := viper.Sub("profiles." + profile)
+		if settings == nil {
+			return nil, false, fmt.Errorf("'%s' is not a file, and '%s' is not a profile in the config file", side, profile)
+		}
+		// the host and credentials are not taken from the current settings,
+		// so the current credentials are never sent to the host of another profile
+		for _, key := range []string{"host", "user", "password"} {
+			if !settings.IsSet(key) {
+				return nil, false, fmt.Errorf("profile '%s' has no %s, it is required", profile, key)
+			}
+		}
+		if settings.IsSet("scheme") {
+			api.Config.Scheme = settings.GetString("scheme")
+		}
+		if settings.IsSet("api-port") {
+			api.Config.ApiPort = settings.GetInt("api-port")
+		}
+		api.Config.Host = settings.GetString("host")
+		api.Config.User = settings.GetString("user")
+		api.Config.Password = settings.GetString("password")
+	}
+
+	definition, err := getDefinition(vhostName)
+	if err == nil && len(vhostName) > 0 {
+		err = getVhostPermissions(vhostName, definition)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read the definitions of '%s': %v", side, err)
+	}
+	return definition, len(vhostName) > 0, nil
+}
+
+// stripVHosts clears the vhost of all objects in the vhost
+func stripVHosts(d *vhost.Definition) {
+	for _, p := range d.Permissions {
+		p.VHost = ""
+	}
+	for _, p := range d.TopicPermissions {
+		p.VHost = ""
+	}
+	for _, p := range d.Policies {
+		p.VHost = ""
+	}
+	for _, q := range d.Queues {
+		q.VHost = ""
+	}
+	for _, e := range d.Exchanges {
+		e.VHost = ""
+	}
+	for _, b := range d.Bindings {
+		b.VHost = ""
+	}
+	for _, p := range d.Parameters {
+		p.VHost = ""
+	}
+}
+
+// changeTitle returns a readable description of the changed object
+func changeTitle(c *vhost.Change) string {
+	title := fmt.Sprintf("%s '%s'", c.Type, c.Name)
+	if len(c.VHost) > 0 {
+		title += fmt.Sprintf(" in vhost '%s'", c.VHost)
+	}
+	return title
+}
+
+// printChanges prints the changes, and the changed fields with their source and target values
+func printChanges(changes []*vhost.Change) {
+	symbols := map[string]string{vhost.Added: "+", vhost.Removed: "-", vhost.Changed: "~"}
+	counts := map[string]int{}
+	for _, c := range changes {
+		counts[c.Action]++
+		fmt.Printf("%s %s %s\n", symbols[c.Action], changeTitle(c), c.Action)
+		for _, f := range c.Fields {
+			source := c.Source.(map[string]interface{})[f]
+			target := c.Target.(map[string]interface{})[f]
+			fmt.Printf("      %s: %s => %s\n", f, planValue(source), planValue(target))
+		}
+	}
+	fmt.Printf("%d added, %d removed, %d changed\n", counts[vhost.Added], counts[vhost.Removed], counts[vhost.Changed])
+}
+
+// printUnifiedDiff prints the changes as a unified diff of the indented JSON of the objects
+func printUnifiedDiff(source, target string, changes []*vhost.Change) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Printf("--- %s\n+++ %s\n", source, target)
+	for _, c := range changes {
+		sourceLines, targetLines := jsonLines(c.Source), jsonLines(c.Target)
+		fmt.Printf("@@ -%s +%s @@ %s\n", hunkRange(sourceLines), hunkRange(targetLines), changeTitle(c))
+		for _, line := range diffLines(sourceLines, targetLines) {
+			fmt.Println(line)
+		}
+	}
+}
+
+func hunkRange(lines []string) string {
+	if len(lines) == 0 {
+		return "0,0"
+	}
+	return fmt.Sprintf("1,%d", len(lines))
+}
+
+func jsonLines(value interface{}) []string {
+	if value == nil {
+		return []string{}
+	}
+	data, _ := json.MarshalIndent(value, "", "  ")
+	return strings.Split(string(data), "\n")
+}
+
+// diffLines returns the lines of a and b, prefixed with '-' when they are only in a,
+// '+' when they are only in b, and ' ' when they are in both, using the longest common subsequence
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := []string{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, " "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "-"+a[i])
+			i++
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, "-"+a[i])
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, "+"+b[j])
+	}
+	return lines
+}
+
+var (
+	diffFormat string
+)
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.PersistentFlags().StringVar(&diffFormat, "format", "human", "The output format: "+strings.Join(diffFormats, ", "))
+}
@@ -0,0 +1,167 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// readValue returns the decoded JSON or YAML value, the value is read from a file when it starts with '@'
+func readValue(value string) (interface{}, error) {
+	data := []byte(value)
+	if strings.HasPrefix(value, "@") {
+		file, err := ioutil.ReadFile(value[1:])
+		if err != nil {
+			return nil, err
+		}
+		data = file
+	}
+
+	var result interface{}
+	if err := decodeJsonOrYaml(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid value, expected JSON or YAML: %v", err)
+	}
+	return result, nil
+}
+
+// decodeJsonOrYaml decodes the data into v, the data is decoded as YAML when it is not valid JSON
+func decodeJsonOrYaml(data []byte, v interface{}) error {
+	if json.Valid(data) {
+		return json.Unmarshal(data, v)
+	}
+
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	// YAML maps decode to map[interface{}]interface{}, which cannot be
+	// marshalled to JSON, so convert them before decoding into v
+	converted, err := json.Marshal(yamlToJson(document))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(converted, v)
+}
+
+// yamlToJson converts the YAML maps in the value to JSON compatible maps
+func yamlToJson(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = yamlToJson(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = yamlToJson(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// printIndentedJson writes the value as indented JSON to stdout
+func printIndentedJson(value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "\t")
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(data)
+	os.Stdout.WriteString("\n")
+	return nil
+}
+
+// writeJsonOrYaml writes the value as indented JSON, or as YAML when format is 'yaml'
+func writeJsonOrYaml(w io.Writer, value interface{}, format string) error {
+	data, err := json.MarshalIndent(value, "", "\t")
+	if err != nil {
+		return err
+	}
+	if format != "yaml" {
+		_, err := w.Write(append(data, '\n'))
+		return err
+	}
+
+	// decode the JSON into yaml.MapSlices, to keep the order of the fields
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	document, err := decodeOrdered(decoder)
+	if err != nil {
+		return err
+	}
+	data, err = yaml.Marshal(document)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// decodeOrdered decodes the next JSON value, objects are decoded into yaml.MapSlices
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		if t == '[' {
+			items := []interface{}{}
+			for decoder.More() {
+				item, err := decodeOrdered(decoder)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+			_, err := decoder.Token()
+			return items, err
+		}
+
+		object := yaml.MapSlice{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, yaml.MapItem{Key: key, Value: value})
+		}
+		_, err := decoder.Token()
+		return object, err
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	default:
+		return t, nil
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/LogiqsAgro/rmq/api/vhost"
+	"github.com/spf13/cobra"
+)
+
+// exportDefinitionsCmd represents the export definitions command
+var exportDefinitionsCmd = &cobra.Command{
+	Use:   "definitions",
+	Short: "Exports the definitions in a stable order, to keep diffs between exports small",
+	Long: `Exports the definitions of all vhosts, or only of the vhost when --vhost is given, as indented JSON or YAML.
+
+All collections are sorted by stable keys, and the rabbit_version and rabbitmq_version are left out, so exports of the same
+definitions are identical. Use --strip-passwords to leave out the password hashes of the users,
+import and apply warn about users without a password hash.
+
+The definitions can be filtered with --vhost-regex, which matches the vhost of the objects, and
+--name-regex, which matches the names of users, parameters, policies, queues and exchanges,
+and the source or destination of bindings.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if exportFormat != "json" && exportFormat != "yaml" {
+			return fmt.Errorf("invalid --format '%s', expected json or yaml", exportFormat)
+		}
+		if err := validateRegexes(map[string]string{"vhost-regex": exportVHostRegex, "name-regex": exportNameRegex}); err != nil {
+			return err
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		vhostName := ""
+		if cmd.Flags().Changed("vhost") {
+			vhostName = api.Config.VHost
+		}
+		definition, err := getDefinition(vhostName)
+		if err != nil {
+			return err
+		}
+
+		definition.RabbitVersion = ""
+		definition.RabbitMQVersion = ""
+		if exportStripPasswords {
+			for _, u := range definition.Users {
+				u.PasswordHash = ""
+			}
+		}
+		filterDefinition(definition, regexp.MustCompile(exportVHostRegex), regexp.MustCompile(exportNameRegex))
+		definition.Sort()
+
+		out := io.Writer(os.Stdout)
+		if len(exportOutput) > 0 {
+			file, err := os.Create(exportOutput)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			out = file
+		}
+		return writeJsonOrYaml(out, definition, exportFormat)
+	},
+}
+
+// filterDefinition removes the objects whose vhost does not match vhostRegex, or whose name does not match nameRegex,
+// the collections are filtered in place, so collections that are not in the definitions stay nil
+func filterDefinition(d *vhost.Definition, vhostRegex, nameRegex *regexp.Regexp) {
+	users := d.Users[:0]
+	for _, u := range d.Users {
+		if nameRegex.MatchString(u.Name) {
+			users = append(users, u)
+		}
+	}
+	d.Users = users
+
+	vhosts := d.VHosts[:0]
+	for _, v := range d.VHosts {
+		if vhostRegex.MatchString(v.Name) {
+			vhosts = append(vhosts, v)
+		}
+	}
+	d.VHosts = vhosts
+
+	permissions := d.Permissions[:0]
+	for _, p := range d.Permissions {
+		if vhostRegex.MatchString(p.VHost) {
+			permissions = append(permissions, p)
+		}
+	}
+	d.Permissions = permissions
+
+	topicPermissions := d.TopicPermissions[:0]
+	for _, p := range d.TopicPermissions {
+		if vhostRegex.MatchString(p.VHost) {
+			topicPermissions = append(topicPermissions, p)
+		}
+	}
+	d.TopicPermissions = topicPermissions
+
+	parameters := d.Parameters[:0]
+	for _, p := range d.Parameters {
+		if vhostRegex.MatchString(p.VHost) && nameRegex.MatchString(p.Name) {
+			parameters = append(parameters, p)
+		}
+	}
+	d.Parameters = parameters
+
+	globalParameters := d.GlobalParameters[:0]
+	for _, p := range d.GlobalParameters {
+		if nameRegex.MatchString(p.Name) {
+			globalParameters = append(globalParameters, p)
+		}
+	}
+	d.GlobalParameters = globalParameters
+
+	policies := d.Policies[:0]
+	for _, p := range d.Policies {
+		if vhostRegex.MatchString(p.VHost) && nameRegex.MatchString(p.Name) {
+			policies = append(policies, p)
+		}
+	}
+	d.Policies = policies
+
+	queues := d.Queues[:0]
+	for _, q := range d.Queues {
+		if vhostRegex.MatchString(q.VHost) && nameRegex.MatchString(q.Name) {
+			queues = append(queues, q)
+		}
+	}
+	d.Queues = queues
+
+	exchanges := d.Exchanges[:0]
+	for _, e := range d.Exchanges {
+		if vhostRegex.MatchString(e.VHost) && nameRegex.MatchString(e.Name) {
+			exchanges = append(exchanges, e)
+		}
+	}
+	d.Exchanges = exchanges
+
+	bindings := d.Bindings[:0]
+	for _, b := range d.Bindings {
+		if vhostRegex.MatchString(b.VHost) && (nameRegex.MatchString(b.Source) || nameRegex.MatchString(b.Destination)) {
+			bindings = append(bindings, b)
+		}
+	}
+	d.Bindings = bindings
+}
+
+var (
+	exportStripPasswords bool
+	exportVHostRegex     string
+	exportNameRegex      string
+	exportFormat         string
+	exportOutput         string
+)
+
+func init() {
+	exportCmd.AddCommand(exportDefinitionsCmd)
+	flags := exportDefinitionsCmd.PersistentFlags()
+	flags.BoolVar(&exportStripPasswords, "strip-passwords", false, "Leave out the password hashes of the users")
+	flags.StringVar(&exportVHostRegex, "vhost-regex", "", "Only export the objects in vhosts matching this regular expression")
+	flags.StringVar(&exportNameRegex, "name-regex", "", "Only export the objects with names matching this regular expression")
+	flags.StringVar(&exportFormat, "format", "json", "The output format, json or yaml")
+	flags.StringVarP(&exportOutput, "output", "o", "", "Write the definitions to this file instead of stdout")
+}
@@ -121,13 +121,13 @@ func buildGraph(definition *vhost.Definition) (*gographviz.Graph, error) {
 
 		source, ok := exchanges[b.Source]
 		if !ok {
-			return nil, fmt.Errorf("could not find source exchange %s for binding", b.Source)
+			return nil, fmt.Errorf("could not find source exchange %s for binding, use 'rmq lint' to list all integrity problems", b.Source)
 		}
 
 		if b.DestinationType == "queue" {
 			target, ok := queues[b.Destination]
 			if !ok {
-				return nil, fmt.Errorf("could not find destination %s %s for binding", b.DestinationType, b.Destination)
+				return nil, fmt.Errorf("could not find destination %s %s for binding, use 'rmq lint' to list all integrity problems", b.DestinationType, b.Destination)
 			}
 			g.AddEdge(source, target, true, map[string]string{
 				// "label": quoted("x to q"),
@@ -136,7 +136,7 @@ func buildGraph(definition *vhost.Definition) (*gographviz.Graph, error) {
 		} else {
 			target, ok := exchanges[b.Destination]
 			if !ok {
-				return nil, fmt.Errorf("could not find destination %s %s for binding", b.DestinationType, b.Destination)
+				return nil, fmt.Errorf("could not find destination %s %s for binding, use 'rmq lint' to list all integrity problems", b.DestinationType, b.Destination)
 			}
 			g.AddEdge(source, target, true, map[string]string{
 				// "label": quoted("x to x"),
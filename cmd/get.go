@@ -0,0 +1,315 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// getCmd represents the get command
+var getCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Gets messages from a queue",
+	Long: `Gets up to --count messages from the queue with the given --name.
+
+The --ackmode determines if the messages stay in the queue:
+  ack_requeue_true, reject_requeue_true   : the messages are requeued, use these to peek at messages
+  ack_requeue_false, reject_requeue_false : the messages are removed from the queue
+
+The messages are printed as json, or use --format human to print the properties,
+headers (including the x-death dead-lettering history) and the decoded payload.
+Use --out-dir to write the payload of each message to its own file.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(getName) == 0 {
+			return fmt.Errorf("--name ( or -n ) is a required parameter")
+		}
+		if getCount < 1 {
+			return fmt.Errorf("--count must be at least 1")
+		}
+		if !contains(getAckModes, getAckMode) {
+			return fmt.Errorf("invalid ackmode '%s', use one of %s", getAckMode, strings.Join(getAckModes, ", "))
+		}
+		if !contains(getEncodings, getEncoding) {
+			return fmt.Errorf("invalid encoding '%s', use one of %s", getEncoding, strings.Join(getEncodings, ", "))
+		}
+		if !contains(getFormats, getFormat) {
+			return fmt.Errorf("invalid format '%s', use one of %s", getFormat, strings.Join(getFormats, ", "))
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		body := map[string]interface{}{
+			"count":    getCount,
+			"ackmode":  getAckMode,
+			"encoding": getEncoding,
+		}
+		if getTruncate > 0 {
+			body["truncate"] = getTruncate
+		}
+
+		data := json.RawMessage{}
+		if _, err := execute(api.PostQueueGetForVhost(api.Config.VHost, getName).Body(body), &data); err != nil {
+			return err
+		}
+
+		messages := []*getMessage{}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return err
+		}
+
+		if len(getOutDir) > 0 {
+			if err := writeMessageFiles(getOutDir, messages); err != nil {
+				return err
+			}
+		}
+
+		if getFormat == "human" {
+			for i, m := range messages {
+				printMessage(i+1, len(messages), m)
+			}
+			return nil
+		}
+
+		if api.Config.IndentJson {
+			indented := &bytes.Buffer{}
+			if err := json.Indent(indented, data, "", "\t"); err == nil {
+				data = indented.Bytes()
+			}
+		}
+		os.Stdout.Write(data)
+		os.Stdout.WriteString("\n")
+		return nil
+	},
+}
+
+type (
+	// getMessage is a message returned by the queue get endpoint
+	getMessage struct {
+		PayloadBytes    int               `json:"payload_bytes"`
+		Redelivered     bool              `json:"redelivered"`
+		Exchange        string            `json:"exchange"`
+		RoutingKey      string            `json:"routing_key"`
+		MessageCount    int               `json:"message_count"`
+		Properties      messageProperties `json:"properties"`
+		Payload         string            `json:"payload"`
+		PayloadEncoding string            `json:"payload_encoding"`
+	}
+
+	// messageProperties are the properties of a message, the api returns an empty list when a message has no properties
+	messageProperties map[string]interface{}
+)
+
+func (p *messageProperties) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("[]")) {
+		*p = messageProperties{}
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string]interface{})(p))
+}
+
+// body returns the decoded message payload
+func (m *getMessage) body() ([]byte, error) {
+	if m.PayloadEncoding == "base64" {
+		return base64.StdEncoding.DecodeString(m.Payload)
+	}
+	return []byte(m.Payload), nil
+}
+
+func (m *getMessage) contentType() string {
+	if ct, ok := m.Properties["content_type"].(string); ok {
+		return ct
+	}
+	return ""
+}
+
+// writeMessageFiles writes the payload of each message to its own file in dir
+func writeMessageFiles(dir string, messages []*getMessage) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i, m := range messages {
+		body, err := m.body()
+		if err != nil {
+			return err
+		}
+		name := filepath.Join(dir, fmt.Sprintf("message-%04d%s", i+1, fileExtension(m.contentType())))
+		if err := os.WriteFile(name, body, 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "wrote %d bytes to %s\n", len(body), name)
+	}
+	return nil
+}
+
+func fileExtension(contentType string) string {
+	switch {
+	case strings.Contains(contentType, "json"):
+		return ".json"
+	case strings.Contains(contentType, "xml"):
+		return ".xml"
+	case strings.HasPrefix(contentType, "text/"):
+		return ".txt"
+	default:
+		return ".bin"
+	}
+}
+
+// printMessage prints the message properties, headers and payload in a human readable form
+func printMessage(n, total int, m *getMessage) {
+	fmt.Printf("=== message %d of %d\n", n, total)
+	fmt.Printf("exchange        : %s\n", m.Exchange)
+	fmt.Printf("routing key     : %s\n", m.RoutingKey)
+	fmt.Printf("redelivered     : %v\n", m.Redelivered)
+	fmt.Printf("messages left   : %d\n", m.MessageCount)
+
+	keys := []string{}
+	for k := range m.Properties {
+		if k != "headers" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > 0 {
+		fmt.Println("properties:")
+		for _, k := range keys {
+			fmt.Printf("  %-16s: %s\n", k, formatProperty(k, m.Properties[k]))
+		}
+	}
+
+	if headers, ok := m.Properties["headers"].(map[string]interface{}); ok && len(headers) > 0 {
+		keys = []string{}
+		for k := range headers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Println("headers:")
+		for _, k := range keys {
+			if k == "x-death" {
+				printXDeath(headers[k])
+				continue
+			}
+			fmt.Printf("  %s: %s\n", k, formatValue(headers[k]))
+		}
+	}
+
+	body, err := m.body()
+	if err != nil {
+		fmt.Printf("payload: could not be decoded: %v\n", err)
+		return
+	}
+	if len(body) < m.PayloadBytes {
+		fmt.Printf("payload (truncated to %d of %d bytes):\n", len(body), m.PayloadBytes)
+	} else {
+		fmt.Printf("payload (%d bytes):\n", m.PayloadBytes)
+	}
+	fmt.Println(formatPayload(body, m.contentType()))
+}
+
+// printXDeath prints the x-death header, that contains the dead-lettering history of the message
+func printXDeath(value interface{}) {
+	deaths, ok := value.([]interface{})
+	if !ok {
+		fmt.Printf("  x-death: %s\n", formatValue(value))
+		return
+	}
+	fmt.Println("  x-death:")
+	for _, d := range deaths {
+		death, ok := d.(map[string]interface{})
+		if !ok {
+			fmt.Printf("    - %s\n", formatValue(d))
+			continue
+		}
+		fmt.Printf("    - count %v, reason %v, queue '%v', exchange '%v', routing keys %s, time %s\n",
+			death["count"], death["reason"], death["queue"], death["exchange"], formatValue(death["routing-keys"]), formatProperty("timestamp", death["time"]))
+	}
+}
+
+// formatProperty formats a property value, timestamps are formatted as a time
+func formatProperty(key string, value interface{}) string {
+	if ts, ok := value.(float64); ok && key == "timestamp" {
+		return time.Unix(int64(ts), 0).UTC().Format(time.RFC3339)
+	}
+	return formatValue(value)
+}
+
+func formatValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// formatPayload formats the payload as indented json for json content,
+// as text when it is valid UTF-8, and as a hex dump otherwise
+func formatPayload(body []byte, contentType string) string {
+	if !utf8.Valid(body) {
+		return hex.Dump(body)
+	}
+	if strings.Contains(contentType, "json") {
+		indented := &bytes.Buffer{}
+		if err := json.Indent(indented, body, "", "  "); err == nil {
+			return indented.String()
+		}
+	}
+	return string(body)
+}
+
+var (
+	getName     string
+	getCount    int
+	getAckMode  string
+	getEncoding string
+	getTruncate int
+	getFormat   string
+	getOutDir   string
+)
+
+var (
+	getAckModes  = []string{"ack_requeue_true", "reject_requeue_true", "ack_requeue_false", "reject_requeue_false"}
+	getEncodings = []string{"auto", "base64"}
+	getFormats   = []string{"json", "human"}
+)
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+	flags := getCmd.PersistentFlags()
+	flags.StringVarP(&getName, "name", "n", "", "The name of the queue to get messages from")
+	flags.IntVarP(&getCount, "count", "c", 1, "The maximum number of messages to get")
+	flags.StringVar(&getAckMode, "ackmode", "ack_requeue_true", "How messages are acknowledged: "+strings.Join(getAckModes, ", "))
+	flags.StringVar(&getEncoding, "encoding", "auto", "The payload encoding: auto (a string when the payload is valid UTF-8, base64 otherwise) or base64")
+	flags.IntVar(&getTruncate, "truncate", 0, "Truncate payloads larger than this number of bytes, 0 disables truncation")
+	flags.StringVar(&getFormat, "format", "json", "The output format: "+strings.Join(getFormats, ", "))
+	flags.StringVar(&getOutDir, "out-dir", "", "Write the payload of each message to its own file in this directory")
+}
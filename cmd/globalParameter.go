@@ -0,0 +1,141 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// globalParameterCmd represents the global-parameter command
+var globalParameterCmd = &cobra.Command{
+	Use:   "global-parameter",
+	Short: "Manages the global runtime parameters",
+	Long: `Manages the global runtime parameters, like cluster_name.
+
+Values are given with --value as inline JSON or YAML, or read from a file with --value @file.json.`,
+	Run: nil,
+}
+
+// globalParameterSetCmd represents the global-parameter set command
+var globalParameterSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Sets the value of a global parameter",
+	Long:  `Sets the value of the global parameter with the given --name`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateGlobalParameterName(cmd, args); err != nil {
+			return err
+		}
+		if len(parameterValue) == 0 {
+			return fmt.Errorf("--value is a required parameter")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		value, err := readValue(parameterValue)
+		if err != nil {
+			return err
+		}
+		req := api.PutGlobalParameter(parameterName).Body(map[string]interface{}{
+			"name":  parameterName,
+			"value": value,
+		})
+		if _, err := execute(req, nil); err != nil {
+			return err
+		}
+		fmt.Printf("set global parameter '%s'\n", parameterName)
+		return nil
+	},
+}
+
+// globalParameterGetCmd represents the global-parameter get command
+var globalParameterGetCmd = &cobra.Command{
+	Use:     "get",
+	Short:   "Prints a global parameter",
+	Long:    `Prints the global parameter with the given --name`,
+	PreRunE: validateGlobalParameterName,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		var parameter interface{}
+		if _, err := execute(api.GetGlobalParameter(parameterName), &parameter); err != nil {
+			if api.IsStatus(err, http.StatusNotFound) {
+				return fmt.Errorf("global parameter '%s' not found", parameterName)
+			}
+			return err
+		}
+		return printIndentedJson(parameter)
+	},
+}
+
+// globalParameterDeleteCmd represents the global-parameter delete command
+var globalParameterDeleteCmd = &cobra.Command{
+	Use:     "delete",
+	Short:   "Deletes a global parameter",
+	Long:    `Deletes the global parameter with the given --name`,
+	PreRunE: validateGlobalParameterName,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if _, err := execute(api.DeleteGlobalParameter(parameterName), nil); err != nil {
+			if api.IsStatus(err, http.StatusNotFound) {
+				return fmt.Errorf("global parameter '%s' not found", parameterName)
+			}
+			return err
+		}
+		fmt.Printf("deleted global parameter '%s'\n", parameterName)
+		return nil
+	},
+}
+
+// globalParameterListCmd represents the global-parameter list command
+var globalParameterListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the global parameters",
+	Long:  `Lists the global parameters`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		var parameters interface{}
+		if _, err := execute(api.GetGlobalParameters(), &parameters); err != nil {
+			return err
+		}
+		return printIndentedJson(parameters)
+	},
+}
+
+func validateGlobalParameterName(cmd *cobra.Command, args []string) error {
+	if len(parameterName) == 0 {
+		return fmt.Errorf("--name ( or -n ) is a required parameter")
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(globalParameterCmd)
+
+	for _, cmd := range []*cobra.Command{globalParameterSetCmd, globalParameterGetCmd, globalParameterDeleteCmd} {
+		globalParameterCmd.AddCommand(cmd)
+		cmd.PersistentFlags().StringVarP(&parameterName, "name", "n", "", "The parameter name")
+	}
+	globalParameterCmd.AddCommand(globalParameterListCmd)
+	globalParameterSetCmd.PersistentFlags().StringVar(&parameterValue, "value", "", "The parameter value as JSON or YAML, or @file to read it from a file")
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// importDefinitionsCmd represents the import definitions command
+var importDefinitionsCmd = &cobra.Command{
+	Use:   "definitions",
+	Short: "Imports the definitions from a JSON or YAML --file",
+	Long: `Imports the definitions (users, vhosts, permissions, policies, queues, exchanges, bindings, etc...)
+from a JSON or YAML --file, into all vhosts, or only into the vhost when --vhost is given.
+
+Before the definitions are uploaded they are validated, and the users, vhosts, policies, queues,
+exchanges and bindings that do not exist yet are listed. Use --dry-run to stop after the validation.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(importDefinitionsFile) == 0 {
+			return fmt.Errorf("--file ( or -f ) is a required parameter")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		vhostName := ""
+		if cmd.Flags().Changed("vhost") {
+			vhostName = api.Config.VHost
+		}
+
+		definition, document, err := readDefinitionFile(importDefinitionsFile)
+		if err != nil {
+			return err
+		}
+		if len(vhostName) > 0 && (len(definition.Users) > 0 || len(definition.VHosts) > 0 || len(definition.Permissions) > 0) {
+			fmt.Printf("note: the users, vhosts and permissions in '%s' are ignored when importing into vhost '%s'\n", importDefinitionsFile, vhostName)
+		}
+
+		live, err := getDefinition(vhostName)
+		if err != nil {
+			return err
+		}
+
+		problems, warnings := validateDefinition(definition, vhostName)
+		for _, w := range warnings {
+			fmt.Println("warning: " + w)
+		}
+		problems = append(problems, validateBindingReferences(definition, live, vhostName)...)
+		if len(problems) > 0 {
+			for _, p := range problems {
+				fmt.Println(p)
+			}
+			return fmt.Errorf("the definitions in '%s' are invalid, %d problem(s) found", importDefinitionsFile, len(problems))
+		}
+
+		created := newDefinitionObjects(definition, live, vhostName)
+		if len(created) == 0 {
+			fmt.Println("no new objects would be created")
+		} else {
+			fmt.Printf("%d new object(s) would be created:\n", len(created))
+			printDefinitionObjects(created)
+		}
+
+		if importDefinitionsDryRun {
+			fmt.Println("dry run: the definitions are not imported")
+			return nil
+		}
+
+		req := api.PostDefinitions()
+		if len(vhostName) > 0 {
+			req = api.PostDefinitionsForVhost(vhostName)
+		}
+		if _, err := execute(req.Body(document), nil); err != nil {
+			return err
+		}
+		fmt.Printf("imported the definitions from '%s'\n", importDefinitionsFile)
+		return nil
+	},
+}
+
+var (
+	importDefinitionsFile   string
+	importDefinitionsDryRun bool
+)
+
+func init() {
+	importCmd.AddCommand(importDefinitionsCmd)
+	flags := importDefinitionsCmd.PersistentFlags()
+	flags.StringVarP(&importDefinitionsFile, "file", "f", "", "The JSON or YAML file with the definitions")
+	flags.BoolVar(&importDefinitionsDryRun, "dry-run", false, "Only validate the definitions, and list the objects that would be created")
+}
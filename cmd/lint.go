@@ -0,0 +1,375 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/LogiqsAgro/rmq/api/vhost"
+	"github.com/spf13/cobra"
+)
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint [live]",
+	Short: "Reports the integrity problems in the definitions of a --file, or of the live broker",
+	Long: `Reports the integrity problems in the definitions of a JSON or YAML --file, or in the live
+definitions of all vhosts, or only of the vhost when --vhost is given.
+
+Each finding has a severity (error, warning or info) and a rule:
+
+  binding-missing-source        error    the source exchange of a binding does not exist
+  binding-missing-destination   error    the destination queue or exchange of a binding does not exist
+  missing-dead-letter-exchange  error    a queue or policy dead-letters to an exchange that does not exist
+  missing-alternate-exchange    error    an exchange or policy has an alternate-exchange that does not exist
+  unused-policy                 warning  the pattern of a policy matches no queues or exchanges
+  unevaluated-policy-pattern    warning  the pattern of a policy cannot be evaluated, see below
+  deprecated-mirroring          warning  a policy uses the deprecated ha-* classic queue mirroring keys
+  unbound-exchange              warning  the exchange has no bindings and no alternate-exchange, its messages are dropped
+  unbound-queue                 info     no bindings have the queue as destination
+
+The built-in amq.* exchanges and the default exchange always exist. RabbitMQ evaluates policy patterns
+as PCRE regular expressions, they are evaluated here as Go regular expressions, which do not support all
+PCRE features, like the look-ahead in '^(?!amq\.).*'. Such patterns are reported as unevaluated-policy-pattern,
+and the policy is ignored by the other rules. The --format is human or json.
+The command exits with exit code 1 when there are findings with the --fail-on severity or higher.`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateDefinitionSource(args, lintFile); err != nil {
+			return err
+		}
+		if !contains(lintFormats, lintFormat) {
+			return fmt.Errorf("invalid --format '%s', expected one of %s", lintFormat, strings.Join(lintFormats, ", "))
+		}
+		if _, ok := severityRanks[lintFailOn]; !ok {
+			return fmt.Errorf("invalid --fail-on '%s', expected one of %s", lintFailOn, strings.Join(severities, ", "))
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		definition, vhostName, err := readDefinitionSource(cmd, lintFile)
+		if err != nil {
+			return err
+		}
+
+		findings := lintDefinition(definition, vhostName)
+		return reportFindings(findings, lintFormat, lintFailOn)
+	},
+}
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+var (
+	severities    = []string{severityError, severityWarning, severityInfo}
+	severityRanks = map[string]int{severityError: 2, severityWarning: 1, severityInfo: 0}
+	lintFormats   = []string{"human", "json"}
+)
+
+// validateDefinitionSource checks that the definitions are read from either the live broker or a file
+func validateDefinitionSource(args []string, file string) error {
+	if len(args) > 0 && args[0] != "live" {
+		return fmt.Errorf("invalid argument '%s', expected 'live' or --file", args[0])
+	}
+	if len(args) > 0 && len(file) > 0 {
+		return fmt.Errorf("use either 'live' or --file ( or -f ), not both")
+	}
+	return nil
+}
+
+// readDefinitionSource reads the definitions from the file, or the live definitions when file is empty,
+// and returns the vhost of the definitions, which is empty unless --vhost is given
+func readDefinitionSource(cmd *cobra.Command, file string) (*vhost.Definition, string, error) {
+	vhostName := ""
+	if cmd.Flags().Changed("vhost") {
+		vhostName = api.Config.VHost
+	}
+
+	if len(file) > 0 {
+		definition, _, err := readDefinitionFile(file)
+		return definition, vhostName, err
+	}
+	definition, err := getDefinition(vhostName)
+	return definition, vhostName, err
+}
+
+// finding is a problem with an object in the definitions, reported by a rule
+type finding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Type     string `json:"type"`
+	VHost    string `json:"vhost"`
+	Name     string `json:"name"`
+	Message  string `json:"message"`
+}
+
+// lintDefinition returns the integrity problems in the definitions, the vhost of the objects
+// is taken from vhostName when it is set
+func lintDefinition(d *vhost.Definition, vhostName string) []*finding {
+	findings := []*finding{}
+	report := func(rule, severity, kind, objectVhost, name, format string, args ...interface{}) {
+		findings = append(findings, &finding{
+			Rule:     rule,
+			Severity: severity,
+			Type:     kind,
+			VHost:    objectVHost(vhostName, objectVhost),
+			Name:     name,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	exchanges := map[definitionObject]bool{}
+	for _, e := range d.Exchanges {
+		exchanges[definitionObject{"exchange", objectVHost(vhostName, e.VHost), e.Name}] = true
+	}
+	queues := map[definitionObject]bool{}
+	for _, q := range d.Queues {
+		queues[definitionObject{"queue", objectVHost(vhostName, q.VHost), q.Name}] = true
+	}
+	exchangeExists := func(objectVhost, name string) bool {
+		return isBuiltinExchange(name) || exchanges[definitionObject{"exchange", objectVHost(vhostName, objectVhost), name}]
+	}
+
+	bound := map[definitionObject]bool{}
+	for _, b := range d.Bindings {
+		v := objectVHost(vhostName, b.VHost)
+		bound[definitionObject{"exchange", v, b.Source}] = true
+		if b.DestinationType == "queue" {
+			bound[definitionObject{"queue", v, b.Destination}] = true
+		}
+
+		if !exchangeExists(b.VHost, b.Source) {
+			report("binding-missing-source", severityError, "binding", b.VHost, b.Name(),
+				"source exchange '%s' does not exist", b.Source)
+		}
+		destinationExists := queues[definitionObject{"queue", v, b.Destination}]
+		if b.DestinationType != "queue" {
+			destinationExists = exchangeExists(b.VHost, b.Destination)
+		}
+		if !destinationExists {
+			report("binding-missing-destination", severityError, "binding", b.VHost, b.Name(),
+				"destination %s '%s' does not exist", b.DestinationType, b.Destination)
+		}
+	}
+
+	for _, q := range d.Queues {
+		if dlx, ok := q.Arguments["x-dead-letter-exchange"].(string); ok && !exchangeExists(q.VHost, dlx) {
+			report("missing-dead-letter-exchange", severityError, "queue", q.VHost, q.Name,
+				"dead-letter exchange '%s' does not exist", dlx)
+		}
+		if !bound[definitionObject{"queue", objectVHost(vhostName, q.VHost), q.Name}] {
+			report("unbound-queue", severityInfo, "queue", q.VHost, q.Name,
+				"queue has no bindings, it only receives messages through the default exchange")
+		}
+	}
+
+	for _, e := range d.Exchanges {
+		if ae, ok := e.Arguments["alternate-exchange"].(string); ok && !exchangeExists(e.VHost, ae) {
+			report("missing-alternate-exchange", severityError, "exchange", e.VHost, e.Name,
+				"alternate-exchange '%s' does not exist", ae)
+		}
+		// messages that cannot be routed go to the alternate-exchange, the argument takes precedence over a policy
+		ae, _ := e.Arguments["alternate-exchange"].(string)
+		if p := effectivePolicy(d, vhostName, e.VHost, "exchange", "", e.Name); len(ae) == 0 && p != nil && p.Definition != nil && p.Definition.AlternateExchange != nil {
+			ae = *p.Definition.AlternateExchange
+		}
+		hasAlternateExchange := len(ae) > 0 && exchangeExists(e.VHost, ae)
+		if !bound[definitionObject{"exchange", objectVHost(vhostName, e.VHost), e.Name}] && !hasAlternateExchange {
+			report("unbound-exchange", severityWarning, "exchange", e.VHost, e.Name,
+				"exchange has no bindings, messages published to it are dropped")
+		}
+	}
+
+	for _, p := range d.Policies {
+		definition := p.Definition
+		if definition == nil {
+			definition = &vhost.PolicyDefinition{}
+		}
+		if dlx := definition.DeadLetterExchange; dlx != nil && !exchangeExists(p.VHost, *dlx) {
+			report("missing-dead-letter-exchange", severityError, "policy", p.VHost, p.Name,
+				"dead-letter exchange '%s' does not exist", *dlx)
+		}
+		if ae := definition.AlternateExchange; ae != nil && !exchangeExists(p.VHost, *ae) {
+			report("missing-alternate-exchange", severityError, "policy", p.VHost, p.Name,
+				"alternate-exchange '%s' does not exist", *ae)
+		}
+
+		keys := []string{}
+		for key := range definition.Map() {
+			if strings.HasPrefix(key, "ha-") {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			sort.Strings(keys)
+			report("deprecated-mirroring", severityWarning, "policy", p.VHost, p.Name,
+				"classic queue mirroring (%s) is deprecated, use quorum queues or streams instead", strings.Join(keys, ", "))
+		}
+
+		pattern, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			report("unevaluated-policy-pattern", severityWarning, "policy", p.VHost, p.Name,
+				"cannot evaluate pattern '%s', it is not a valid Go regular expression: %v", p.Pattern, err)
+			continue
+		}
+		if !policyMatchesAny(d, p, pattern, vhostName) {
+			report("unused-policy", severityWarning, "policy", p.VHost, p.Name,
+				"pattern '%s' matches no %s", p.Pattern, strings.Replace(policyApplyToOrDefault(p.ApplyTo), "_", " ", -1))
+		}
+	}
+
+	sortFindings(findings)
+	return findings
+}
+
+// policyMatchesAny returns true when the pattern of the policy matches a queue or exchange
+// in the vhost of the policy, of the kind the policy applies to
+func policyMatchesAny(d *vhost.Definition, p *vhost.Policy, pattern *regexp.Regexp, vhostName string) bool {
+	v := objectVHost(vhostName, p.VHost)
+	for _, e := range d.Exchanges {
+		if objectVHost(vhostName, e.VHost) == v && policyAppliesTo(p.ApplyTo, "exchange", "") && pattern.MatchString(e.Name) {
+			return true
+		}
+	}
+	for _, q := range d.Queues {
+		if objectVHost(vhostName, q.VHost) == v && policyAppliesTo(p.ApplyTo, "queue", queueType(q)) && pattern.MatchString(q.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// policyAppliesTo returns true when a policy with the apply-to value applies to objects of the kind,
+// queue or exchange, the queueType is the type of the queue
+func policyAppliesTo(applyTo, kind, queueType string) bool {
+	switch policyApplyToOrDefault(applyTo) {
+	case "all":
+		return true
+	case "exchanges":
+		return kind == "exchange"
+	case "queues":
+		return kind == "queue"
+	case "classic_queues":
+		return kind == "queue" && queueType == "classic"
+	case "quorum_queues":
+		return kind == "queue" && queueType == "quorum"
+	case "streams":
+		return kind == "queue" && queueType == "stream"
+	}
+	return false
+}
+
+// effectivePolicy returns the policy that applies to the queue or exchange, the matching policy
+// with the highest priority, or nil when no policy applies. Policies with a pattern that is not a valid
+// Go regular expression are ignored
+func effectivePolicy(d *vhost.Definition, vhostName, objectVhost, kind, queueType, name string) *vhost.Policy {
+	v := objectVHost(vhostName, objectVhost)
+	policies := []*vhost.Policy{}
+	for _, p := range d.Policies {
+		if objectVHost(vhostName, p.VHost) != v || !policyAppliesTo(p.ApplyTo, kind, queueType) {
+			continue
+		}
+		if pattern, err := regexp.Compile(p.Pattern); err == nil && pattern.MatchString(name) {
+			policies = append(policies, p)
+		}
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+	sort.SliceStable(policies, func(i, j int) bool {
+		if policies[i].Priority != policies[j].Priority {
+			return policies[i].Priority > policies[j].Priority
+		}
+		return policies[i].Name < policies[j].Name
+	})
+	return policies[0]
+}
+
+// sortFindings sorts the findings by severity, with the most severe first, and then by rule, vhost, type and name
+func sortFindings(findings []*finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Severity != b.Severity {
+			return severityRanks[a.Severity] > severityRanks[b.Severity]
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		if a.VHost != b.VHost {
+			return a.VHost < b.VHost
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Name < b.Name
+	})
+}
+
+// reportFindings prints the findings in the format, and returns an error when there are
+// findings with the failOn severity or higher
+func reportFindings(findings []*finding, format, failOn string) error {
+	counts := map[string]int{}
+	failures := 0
+	for _, f := range findings {
+		counts[f.Severity]++
+		if severityRanks[f.Severity] >= severityRanks[failOn] {
+			failures++
+		}
+	}
+
+	if format == "json" {
+		if err := printIndentedJson(findings); err != nil {
+			return err
+		}
+	} else {
+		if len(findings) > 0 {
+			rows := [][]string{}
+			for _, f := range findings {
+				rows = append(rows, []string{strings.ToUpper(f.Severity), f.Rule, f.Type, f.VHost, f.Name, f.Message})
+			}
+			printTable([]string{"SEVERITY", "RULE", "TYPE", "VHOST", "NAME", "MESSAGE"}, rows)
+		}
+		fmt.Printf("%d error(s), %d warning(s), %d info\n", counts[severityError], counts[severityWarning], counts[severityInfo])
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d finding(s) with severity %s or higher", failures, failOn)
+	}
+	return nil
+}
+
+var (
+	lintFile   string
+	lintFormat string
+	lintFailOn string
+)
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+	flags := lintCmd.PersistentFlags()
+	flags.StringVarP(&lintFile, "file", "f", "", "The JSON or YAML definitions file to lint, instead of the live definitions")
+	flags.StringVar(&lintFormat, "format", "human", "The output format: "+strings.Join(lintFormats, ", "))
+	flags.StringVar(&lintFailOn, "fail-on", severityError, "Exit with exit code 1 on findings with this severity or higher: "+strings.Join(severities, ", "))
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/LogiqsAgro/rmq/api/vhost"
+)
+
+func Test_lintDefinition(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition *vhost.Definition
+		vhostName  string
+		expected   []finding
+	}{
+		{
+			name: "bindings are checked in the vhost of the binding",
+			definition: &vhost.Definition{
+				Queues: []*vhost.Queue{
+					{VHost: "a", Name: "q", Durable: true},
+					{VHost: "b", Name: "q", Durable: true},
+				},
+				Exchanges: []*vhost.Exchange{
+					{VHost: "a", Name: "events", Type: "topic", Durable: true},
+				},
+				Bindings: []*vhost.Binding{
+					{VHost: "a", Source: "events", Destination: "q", DestinationType: "queue", RoutingKey: "#"},
+					{VHost: "b", Source: "events", Destination: "q", DestinationType: "queue", RoutingKey: "#"},
+					{VHost: "b", Source: "amq.direct", Destination: "gone", DestinationType: "exchange", RoutingKey: "gone"},
+				},
+			},
+			expected: []finding{
+				{"binding-missing-destination", "error", "binding", "b", "amq.direct -> exchange gone (gone)", "destination exchange 'gone' does not exist"},
+				{"binding-missing-source", "error", "binding", "b", "events -> queue q (#)", "source exchange 'events' does not exist"},
+			},
+		},
+		{
+			name:      "objects of per-vhost definitions are in the given vhost",
+			vhostName: "orders",
+			definition: &vhost.Definition{
+				Queues: []*vhost.Queue{
+					{Name: "q", Durable: true, Arguments: map[string]interface{}{"x-dead-letter-exchange": "dlx"}},
+				},
+			},
+			expected: []finding{
+				{"missing-dead-letter-exchange", "error", "queue", "orders", "q", "dead-letter exchange 'dlx' does not exist"},
+				{"unbound-queue", "info", "queue", "orders", "q", "queue has no bindings, it only receives messages through the default exchange"},
+			},
+		},
+		{
+			name: "exchanges without bindings but with an alternate-exchange are not reported",
+			definition: &vhost.Definition{
+				Exchanges: []*vhost.Exchange{
+					{VHost: "/", Name: "argument", Type: "direct", Arguments: map[string]interface{}{"alternate-exchange": "amq.fanout"}},
+					{VHost: "/", Name: "policy", Type: "direct"},
+					{VHost: "/", Name: "missing", Type: "direct", Arguments: map[string]interface{}{"alternate-exchange": "nowhere"}},
+					{VHost: "other", Name: "policy", Type: "direct"},
+				},
+				Policies: []*vhost.Policy{
+					{VHost: "/", Name: "ae", Pattern: "^policy$", ApplyTo: "exchanges", Definition: vhost.NewPolicyDefinition(map[string]interface{}{"alternate-exchange": "amq.fanout"})},
+				},
+			},
+			expected: []finding{
+				{"missing-alternate-exchange", "error", "exchange", "/", "missing", "alternate-exchange 'nowhere' does not exist"},
+				{"unbound-exchange", "warning", "exchange", "/", "missing", "exchange has no bindings, messages published to it are dropped"},
+				{"unbound-exchange", "warning", "exchange", "other", "policy", "exchange has no bindings, messages published to it are dropped"},
+			},
+		},
+		{
+			name: "policies",
+			definition: &vhost.Definition{
+				Queues: []*vhost.Queue{
+					{VHost: "/", Name: "orders", Durable: true},
+					{VHost: "/", Name: "stream", Durable: true, Arguments: map[string]interface{}{"x-queue-type": "stream"}},
+				},
+				Exchanges: []*vhost.Exchange{
+					{VHost: "/", Name: "events", Type: "topic", Durable: true},
+				},
+				Bindings: []*vhost.Binding{
+					{VHost: "/", Source: "events", Destination: "orders", DestinationType: "queue", RoutingKey: "#"},
+					{VHost: "/", Source: "events", Destination: "stream", DestinationType: "queue", RoutingKey: "#"},
+				},
+				Policies: []*vhost.Policy{
+					{VHost: "/", Name: "ha", Pattern: "^orders$", ApplyTo: "queues", Definition: vhost.NewPolicyDefinition(map[string]interface{}{"ha-mode": "all", "ha-sync-mode": "automatic"})},
+					{VHost: "/", Name: "streams", Pattern: "^stream$", ApplyTo: "streams", Definition: vhost.NewPolicyDefinition(map[string]interface{}{"max-age": "1D"})},
+					{VHost: "/", Name: "quorum", Pattern: "^orders$", ApplyTo: "quorum_queues", Definition: vhost.NewPolicyDefinition(map[string]interface{}{"delivery-limit": 5})},
+					{VHost: "/", Name: "dlx", Pattern: "^events$", ApplyTo: "exchanges", Definition: vhost.NewPolicyDefinition(map[string]interface{}{"dead-letter-exchange": "other"})},
+					{VHost: "/", Name: "pcre", Pattern: `^(?!amq\.).*`, Definition: vhost.NewPolicyDefinition(map[string]interface{}{"max-length": 1})},
+				},
+			},
+			expected: []finding{
+				{"missing-dead-letter-exchange", "error", "policy", "/", "dlx", "dead-letter exchange 'other' does not exist"},
+				{"deprecated-mirroring", "warning", "policy", "/", "ha", "classic queue mirroring (ha-mode, ha-sync-mode) is deprecated, use quorum queues or streams instead"},
+				{"unevaluated-policy-pattern", "warning", "policy", "/", "pcre", "cannot evaluate pattern '^(?!amq\\.).*', it is not a valid Go regular expression: error parsing regexp: invalid or unsupported Perl syntax: `(?!`"},
+				{"unused-policy", "warning", "policy", "/", "quorum", "pattern '^orders$' matches no quorum queues"},
+			},
+		},
+		{
+			name: "valid definitions",
+			definition: &vhost.Definition{
+				Queues:    []*vhost.Queue{{VHost: "/", Name: "q", Durable: true}},
+				Exchanges: []*vhost.Exchange{{VHost: "/", Name: "x", Type: "direct", Durable: true}},
+				Bindings:  []*vhost.Binding{{VHost: "/", Source: "x", Destination: "q", DestinationType: "queue", RoutingKey: "q"}},
+			},
+			expected: []finding{},
+		},
+	}
+
+	for _, test := range tests {
+		actual := []finding{}
+		for _, f := range lintDefinition(test.definition, test.vhostName) {
+			actual = append(actual, *f)
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: lintDefinition() = %+v, expected %+v", test.name, actual, test.expected)
+		}
+	}
+}
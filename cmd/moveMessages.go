@@ -0,0 +1,166 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// moveMessagesCmd represents the move-messages command
+var moveMessagesCmd = &cobra.Command{
+	Use:   "move-messages",
+	Short: "Moves the messages from one queue to another queue",
+	Long: `Moves the messages from the --from queue to the --to queue, optionally in another vhost using --to-vhost.
+
+The messages are moved by a temporary dynamic shovel, that deletes itself after it moved
+the number of messages that were in the source queue when the shovel started.
+The shovel plugin (rabbitmq_shovel) must be enabled.
+
+The progress is polled from the queue statistics, which are updated periodically by RabbitMQ,
+so the reported counts are approximate. The shovel is deleted when the move completes,
+when the --timeout expires, or when the command is interrupted with Ctrl-C.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(moveMessagesFrom) == 0 || len(moveMessagesTo) == 0 {
+			return fmt.Errorf("--from and --to are required parameters")
+		}
+		if len(moveMessagesToVHost) == 0 {
+			moveMessagesToVHost = api.Config.VHost
+		}
+		if moveMessagesFrom == moveMessagesTo && moveMessagesToVHost == api.Config.VHost {
+			return fmt.Errorf("the --from and --to queues must be different")
+		}
+		if moveMessagesInterval <= 0 {
+			return fmt.Errorf("--interval must be greater than 0")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		vhost := api.Config.VHost
+		source, err := getQueue(vhost, moveMessagesFrom)
+		if err != nil {
+			return err
+		}
+		if _, err := getQueue(moveMessagesToVHost, moveMessagesTo); err != nil {
+			return err
+		}
+
+		total := source.Messages
+		if total == 0 {
+			fmt.Printf("queue '%s' in vhost '%s' has no messages to move\n", moveMessagesFrom, vhost)
+			return nil
+		}
+
+		name := fmt.Sprintf("rmq-move-messages-%s-%d", moveMessagesFrom, time.Now().Unix())
+		shovel := map[string]interface{}{
+			"src-protocol":     "amqp091",
+			"src-uri":          "amqp:///" + url.PathEscape(vhost),
+			"src-queue":        moveMessagesFrom,
+			"src-delete-after": "queue-length",
+			"dest-protocol":    "amqp091",
+			"dest-uri":         "amqp:///" + url.PathEscape(moveMessagesToVHost),
+			"dest-queue":       moveMessagesTo,
+			"ack-mode":         "on-confirm",
+		}
+
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, os.Interrupt)
+		defer signal.Stop(interrupted)
+
+		req := api.PutParameterForComponentAndVhost("shovel", vhost, name).Body(map[string]interface{}{"value": shovel})
+		if _, err := execute(req, nil); err != nil {
+			return fmt.Errorf("failed to create shovel '%s': %s", name, errorReason(err))
+		}
+		defer deleteShovel(vhost, name)
+
+		fmt.Printf("moving %d message(s) from queue '%s' in vhost '%s' to queue '%s' in vhost '%s' using shovel '%s'\n",
+			total, moveMessagesFrom, vhost, moveMessagesTo, moveMessagesToVHost, name)
+
+		var timeout <-chan time.Time
+		if moveMessagesTimeout > 0 {
+			timeout = time.After(moveMessagesTimeout)
+		}
+		ticker := time.NewTicker(moveMessagesInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-interrupted:
+				return fmt.Errorf("interrupted, the shovel is deleted, the remaining messages stay in queue '%s'", moveMessagesFrom)
+			case <-timeout:
+				return fmt.Errorf("timed out after %v, the shovel is deleted, the remaining messages stay in queue '%s'", moveMessagesTimeout, moveMessagesFrom)
+			case <-ticker.C:
+			}
+
+			q, err := getQueue(vhost, moveMessagesFrom)
+			if err != nil {
+				return err
+			}
+			moved := total - q.Messages
+			if moved < 0 {
+				moved = 0
+			}
+			fmt.Printf("moved ~%d of %d message(s), %d message(s) left in queue '%s'\n", moved, total, q.Messages, moveMessagesFrom)
+
+			// the shovel deletes its parameter when it has moved all messages
+			_, err = execute(api.GetParameterForComponentAndVhost("shovel", vhost, name), nil)
+			if api.IsStatus(err, http.StatusNotFound) {
+				fmt.Printf("done, moved %d message(s) from queue '%s' to queue '%s'\n", total, moveMessagesFrom, moveMessagesTo)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	},
+}
+
+// deleteShovel deletes the shovel parameter, if it still exists
+func deleteShovel(vhost, name string) {
+	_, err := execute(api.DeleteParameterForComponentAndVhost("shovel", vhost, name), nil)
+	if err == nil {
+		fmt.Printf("deleted shovel '%s'\n", name)
+	} else if !api.IsStatus(err, http.StatusNotFound) {
+		writeError(fmt.Errorf("failed to delete shovel '%s', delete it manually: %s\n", name, errorReason(err)))
+	}
+}
+
+var (
+	moveMessagesFrom     string
+	moveMessagesTo       string
+	moveMessagesToVHost  string
+	moveMessagesInterval time.Duration
+	moveMessagesTimeout  time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(moveMessagesCmd)
+	flags := moveMessagesCmd.PersistentFlags()
+	flags.StringVar(&moveMessagesFrom, "from", "", "The queue to move the messages from")
+	flags.StringVar(&moveMessagesTo, "to", "", "The queue to move the messages to")
+	flags.StringVar(&moveMessagesToVHost, "to-vhost", "", "The vhost of the --to queue, defaults to --vhost")
+	flags.DurationVar(&moveMessagesInterval, "interval", 2*time.Second, "The interval between progress updates")
+	flags.DurationVar(&moveMessagesTimeout, "timeout", 0, "Stop moving messages after this duration, 0 disables the timeout")
+}
@@ -0,0 +1,166 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// parameterCmd represents the parameter command
+var parameterCmd = &cobra.Command{
+	Use:   "parameter",
+	Short: "Manages the runtime parameters of components in a vhost",
+	Long: `Manages the runtime parameters of components, like shovel and federation-upstream, in a vhost.
+
+Values are given with --value as inline JSON or YAML, or read from a file with --value @file.json.`,
+	Run: nil,
+}
+
+// parameterSetCmd represents the parameter set command
+var parameterSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Sets the value of a runtime parameter",
+	Long:  `Sets the value of the runtime parameter with the given --component and --name in the vhost`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateParameterFlags(cmd, args); err != nil {
+			return err
+		}
+		if len(parameterValue) == 0 {
+			return fmt.Errorf("--value is a required parameter")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		value, err := readValue(parameterValue)
+		if err != nil {
+			return err
+		}
+		req := api.PutParameterForComponentAndVhost(parameterComponent, api.Config.VHost, parameterName).Body(map[string]interface{}{
+			"component": parameterComponent,
+			"vhost":     api.Config.VHost,
+			"name":      parameterName,
+			"value":     value,
+		})
+		if _, err := execute(req, nil); err != nil {
+			return err
+		}
+		fmt.Printf("set parameter '%s' of component '%s' in vhost '%s'\n", parameterName, parameterComponent, api.Config.VHost)
+		return nil
+	},
+}
+
+// parameterGetCmd represents the parameter get command
+var parameterGetCmd = &cobra.Command{
+	Use:     "get",
+	Short:   "Prints a runtime parameter",
+	Long:    `Prints the runtime parameter with the given --component and --name in the vhost`,
+	PreRunE: validateParameterFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		var parameter interface{}
+		if _, err := execute(api.GetParameterForComponentAndVhost(parameterComponent, api.Config.VHost, parameterName), &parameter); err != nil {
+			if api.IsStatus(err, http.StatusNotFound) {
+				return fmt.Errorf("parameter '%s' of component '%s' not found in vhost '%s'", parameterName, parameterComponent, api.Config.VHost)
+			}
+			return err
+		}
+		return printIndentedJson(parameter)
+	},
+}
+
+// parameterDeleteCmd represents the parameter delete command
+var parameterDeleteCmd = &cobra.Command{
+	Use:     "delete",
+	Short:   "Deletes a runtime parameter",
+	Long:    `Deletes the runtime parameter with the given --component and --name in the vhost`,
+	PreRunE: validateParameterFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if _, err := execute(api.DeleteParameterForComponentAndVhost(parameterComponent, api.Config.VHost, parameterName), nil); err != nil {
+			if api.IsStatus(err, http.StatusNotFound) {
+				return fmt.Errorf("parameter '%s' of component '%s' not found in vhost '%s'", parameterName, parameterComponent, api.Config.VHost)
+			}
+			return err
+		}
+		fmt.Printf("deleted parameter '%s' of component '%s' in vhost '%s'\n", parameterName, parameterComponent, api.Config.VHost)
+		return nil
+	},
+}
+
+// parameterListCmd represents the parameter list command
+var parameterListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the runtime parameters in the vhost",
+	Long:  `Lists the runtime parameters in the vhost, of all components or only of the given --component`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		parameters := []map[string]interface{}{}
+		if len(parameterComponent) > 0 {
+			if _, err := execute(api.GetParametersForComponentAndVhost(parameterComponent, api.Config.VHost), &parameters); err != nil {
+				return err
+			}
+			return printIndentedJson(parameters)
+		}
+
+		if _, err := execute(api.GetParameters(), &parameters); err != nil {
+			return err
+		}
+		inVHost := []map[string]interface{}{}
+		for _, p := range parameters {
+			if p["vhost"] == api.Config.VHost {
+				inVHost = append(inVHost, p)
+			}
+		}
+		return printIndentedJson(inVHost)
+	},
+}
+
+func validateParameterFlags(cmd *cobra.Command, args []string) error {
+	if len(parameterComponent) == 0 {
+		return fmt.Errorf("--component ( or -c ) is a required parameter")
+	}
+	if len(parameterName) == 0 {
+		return fmt.Errorf("--name ( or -n ) is a required parameter")
+	}
+	return nil
+}
+
+var (
+	parameterComponent string
+	parameterValue     string
+)
+
+func init() {
+	rootCmd.AddCommand(parameterCmd)
+
+	for _, cmd := range []*cobra.Command{parameterSetCmd, parameterGetCmd, parameterDeleteCmd, parameterListCmd} {
+		parameterCmd.AddCommand(cmd)
+		cmd.PersistentFlags().StringVarP(&parameterComponent, "component", "c", "", "The component, e.g. shovel or federation-upstream")
+		if cmd != parameterListCmd {
+			cmd.PersistentFlags().StringVarP(&parameterName, "name", "n", "", "The parameter name")
+		}
+	}
+	parameterSetCmd.PersistentFlags().StringVar(&parameterValue, "value", "", "The parameter value as JSON or YAML, or @file to read it from a file")
+}
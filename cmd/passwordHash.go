@@ -0,0 +1,106 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// hashingAlgorithms maps the --hashing-algorithm values to the RabbitMQ hashing algorithm names
+var hashingAlgorithms = map[string]string{
+	"sha256": "rabbit_password_hashing_sha256",
+	"sha512": "rabbit_password_hashing_sha512",
+}
+
+// hashPassword computes the password_hash for the RabbitMQ hashing algorithm, like RabbitMQ does:
+// a random 32 bit salt is prepended to the UTF-8 encoded password, the result is hashed,
+// the salt is prepended to the hash, and the result is base64 encoded.
+func hashPassword(password, algorithm string) (string, error) {
+	salt := make([]byte, 4)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return hashPasswordWithSalt(password, algorithm, salt)
+}
+
+func hashPasswordWithSalt(password, algorithm string, salt []byte) (string, error) {
+	var h hash.Hash
+	switch algorithm {
+	case "rabbit_password_hashing_sha256":
+		h = sha256.New()
+	case "rabbit_password_hashing_sha512":
+		h = sha512.New()
+	default:
+		return "", fmt.Errorf("unsupported hashing algorithm '%s'", algorithm)
+	}
+
+	h.Write(salt)
+	h.Write([]byte(password))
+	salted := append(append([]byte{}, salt...), h.Sum(nil)...)
+	return base64.StdEncoding.EncodeToString(salted), nil
+}
+
+// readPassword reads a password from stdin when fromStdin is true,
+// otherwise it prompts twice for the password without echoing it to the terminal
+func readPassword(fromStdin bool) (string, error) {
+	if fromStdin {
+		password, err := stdin.ReadString('\n')
+		password = strings.TrimRight(password, "\r\n")
+		if err != nil && len(password) == 0 {
+			return "", fmt.Errorf("no password read from stdin: %v", err)
+		}
+		if len(password) == 0 {
+			return "", fmt.Errorf("no password read from stdin")
+		}
+		return password, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("cannot prompt for a password, stdin is not a terminal, use --password-stdin to read the password from stdin")
+	}
+
+	fmt.Print("Password: ")
+	password, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	if len(password) == 0 {
+		return "", fmt.Errorf("the password cannot be empty, use --no-password to create a user that cannot log in with a password")
+	}
+
+	fmt.Print("Repeat password: ")
+	repeated, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Equal(password, repeated) {
+		return "", fmt.Errorf("the passwords do not match")
+	}
+	return string(password), nil
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+)
+
+func Test_hashPasswordWithSalt(t *testing.T) {
+	// the example from https://www.rabbitmq.com/passwords.html#computing-password-hash
+	salt := []byte{0x90, 0x8D, 0xC6, 0x0A}
+	hashed, err := hashPasswordWithSalt("test12", "rabbit_password_hashing_sha256", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "kI3GCqW5JLMJa4iX1lo7X4D6XbYqlLgxIs30+P6tENUV2POR"
+	if hashed != expected {
+		t.Errorf("Expected hash '%s', got '%s'", expected, hashed)
+	}
+}
+
+func Test_hashPassword(t *testing.T) {
+	for algorithm, size := range map[string]int{"rabbit_password_hashing_sha256": 32, "rabbit_password_hashing_sha512": 64} {
+		hashed, err := hashPassword("secret", algorithm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		salted, err := base64.StdEncoding.DecodeString(hashed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(salted) != 4+size {
+			t.Errorf("Expected a %d byte salted %s hash, got %d bytes", 4+size, algorithm, len(salted))
+		}
+	}
+
+	a, _ := hashPassword("secret", "rabbit_password_hashing_sha256")
+	b, _ := hashPassword("secret", "rabbit_password_hashing_sha256")
+	if a == b {
+		t.Errorf("Expected different hashes because of the random salt")
+	}
+
+	salted, _ := base64.StdEncoding.DecodeString(a)
+	sum := sha256.Sum256(append(append([]byte{}, salted[:4]...), "secret"...))
+	if string(sum[:]) != string(salted[4:]) {
+		t.Errorf("Expected the hash of the salt and the password after the salt")
+	}
+}
+
+func Test_hashPassword_UnsupportedAlgorithm(t *testing.T) {
+	if _, err := hashPassword("secret", "rabbit_password_hashing_md5"); err == nil {
+		t.Errorf("Expected an error for an unsupported algorithm")
+	}
+}
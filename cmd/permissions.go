@@ -0,0 +1,209 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/LogiqsAgro/rmq/api/vhost"
+	"github.com/spf13/cobra"
+)
+
+// permissionsCmd represents the permissions command
+var permissionsCmd = &cobra.Command{
+	Use:   "permissions",
+	Short: "Manages the permissions of users in vhosts",
+	Long: `Manages the permissions of users in vhosts.
+
+The user whose permissions are managed is set with --username, the --user flag is the user
+that is used to log in to the management api.`,
+	Run: nil,
+}
+
+// permissionsSetCmd represents the permissions set command
+var permissionsSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Sets the permissions of a user in the vhost",
+	Long: `Sets the --configure, --write and --read permissions of the user in the vhost.
+
+The permissions are regular expressions matched against the names of the queues and exchanges,
+use '.*' to allow access to all resources, and '' to deny access to all resources.
+RabbitMQ evaluates the permissions as PCRE regular expressions. They are checked as Go regular expressions,
+which do not support all PCRE features, like the look-ahead in '^(?!amq\.).*', so a permission that
+cannot be checked is sent as is, with a warning.
+
+With --copy-from-user, the permissions and topic permissions of that user in all vhosts
+are copied to the user, instead of setting the permissions given by the flags.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(permissionsUsername) == 0 {
+			return fmt.Errorf("--username ( or -u ) is a required parameter")
+		}
+		if len(permissionsCopyFromUser) > 0 {
+			if cmd.Flags().Changed("configure") || cmd.Flags().Changed("write") || cmd.Flags().Changed("read") {
+				return fmt.Errorf("--copy-from-user cannot be combined with --configure, --write or --read")
+			}
+			return nil
+		}
+		warnUncheckedRegexes(map[string]string{
+			"configure": permissionsConfigure,
+			"write":     permissionsWrite,
+			"read":      permissionsRead,
+		})
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if len(permissionsCopyFromUser) > 0 {
+			return copyPermissions(permissionsCopyFromUser, permissionsUsername)
+		}
+
+		req := api.PutPermissionsForVhostAndUser(api.Config.VHost, permissionsUsername).Body(map[string]interface{}{
+			"configure": permissionsConfigure,
+			"write":     permissionsWrite,
+			"read":      permissionsRead,
+		})
+		if _, err := execute(req, nil); err != nil {
+			return err
+		}
+		fmt.Printf("set permissions of user '%s' in vhost '%s': configure '%s', write '%s', read '%s'\n",
+			permissionsUsername, api.Config.VHost, permissionsConfigure, permissionsWrite, permissionsRead)
+		return nil
+	},
+}
+
+// permissionsClearCmd represents the permissions clear command
+var permissionsClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clears the permissions of a user in the vhost",
+	Long:  `Clears the permissions of a user in the vhost`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(permissionsUsername) == 0 {
+			return fmt.Errorf("--username ( or -u ) is a required parameter")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if _, err := execute(api.DeletePermissionsForVhostAndUser(api.Config.VHost, permissionsUsername), nil); err != nil {
+			if api.IsStatus(err, http.StatusNotFound) {
+				return fmt.Errorf("user '%s' has no permissions in vhost '%s'", permissionsUsername, api.Config.VHost)
+			}
+			return err
+		}
+		fmt.Printf("cleared permissions of user '%s' in vhost '%s'\n", permissionsUsername, api.Config.VHost)
+		return nil
+	},
+}
+
+// validateRegexes returns an error for the first regular expression that does not compile
+func validateRegexes(regexes map[string]string) error {
+	for name, re := range regexes {
+		if _, err := regexp.Compile(re); err != nil {
+			return fmt.Errorf("invalid --%s regular expression '%s': %v", name, re, err)
+		}
+	}
+	return nil
+}
+
+// warnUncheckedRegexes prints a warning for each regular expression that does not compile,
+// RabbitMQ evaluates them as PCRE, which supports more than Go regular expressions
+func warnUncheckedRegexes(regexes map[string]string) {
+	names := []string{}
+	for name := range regexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := regexp.Compile(regexes[name]); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: the --%s '%s' is not checked, it is not a valid Go regular expression: %v\n", name, regexes[name], err)
+		}
+	}
+}
+
+// copyPermissions copies the permissions and topic permissions in all vhosts from one user to another
+func copyPermissions(from, to string) error {
+	permissions := []*vhost.Permission{}
+	if _, err := execute(api.GetUsersPermissions(from), &permissions); err != nil {
+		if api.IsStatus(err, http.StatusNotFound) {
+			return fmt.Errorf("user '%s' not found", from)
+		}
+		return err
+	}
+
+	topicPermissions := []*vhost.TopicPermission{}
+	if _, err := execute(api.GetUsersTopicPermissions(from), &topicPermissions); err != nil {
+		return err
+	}
+
+	if len(permissions) == 0 && len(topicPermissions) == 0 {
+		return fmt.Errorf("user '%s' has no permissions to copy", from)
+	}
+
+	for _, p := range permissions {
+		req := api.PutPermissionsForVhostAndUser(p.VHost, to).Body(map[string]interface{}{
+			"configure": p.Configure,
+			"write":     p.Write,
+			"read":      p.Read,
+		})
+		if _, err := execute(req, nil); err != nil {
+			return err
+		}
+		fmt.Printf("set permissions of user '%s' in vhost '%s': configure '%s', write '%s', read '%s'\n", to, p.VHost, p.Configure, p.Write, p.Read)
+	}
+
+	for _, p := range topicPermissions {
+		req := api.PutTopicPermissionsForVhostAndUser(p.VHost, to).Body(map[string]interface{}{
+			"exchange": p.Exchange,
+			"write":    p.Write,
+			"read":     p.Read,
+		})
+		if _, err := execute(req, nil); err != nil {
+			return err
+		}
+		fmt.Printf("set topic permissions of user '%s' in vhost '%s' for exchange '%s': write '%s', read '%s'\n", to, p.VHost, p.Exchange, p.Write, p.Read)
+	}
+	return nil
+}
+
+var (
+	permissionsUsername     string
+	permissionsConfigure    string
+	permissionsWrite        string
+	permissionsRead         string
+	permissionsCopyFromUser string
+)
+
+func init() {
+	rootCmd.AddCommand(permissionsCmd)
+
+	permissionsCmd.AddCommand(permissionsSetCmd)
+	flags := permissionsSetCmd.PersistentFlags()
+	flags.StringVarP(&permissionsUsername, "username", "u", "", "The user to set the permissions for")
+	flags.StringVar(&permissionsConfigure, "configure", "", "The regular expression for the resources the user can configure")
+	flags.StringVar(&permissionsWrite, "write", "", "The regular expression for the resources the user can write to")
+	flags.StringVar(&permissionsRead, "read", "", "The regular expression for the resources the user can read from")
+	flags.StringVar(&permissionsCopyFromUser, "copy-from-user", "", "Copy the permissions and topic permissions in all vhosts from this user")
+
+	permissionsCmd.AddCommand(permissionsClearCmd)
+	permissionsClearCmd.PersistentFlags().StringVarP(&permissionsUsername, "username", "u", "", "The user to clear the permissions for")
+}
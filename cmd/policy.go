@@ -0,0 +1,255 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// policyCmd represents the policy command
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Manages the policies in the vhost",
+	Long:  ``,
+	Run:   nil,
+}
+
+// operatorPolicyCmd represents the operator-policy command
+var operatorPolicyCmd = &cobra.Command{
+	Use:   "operator-policy",
+	Short: "Manages the operator policies in the vhost",
+	Long: `Manages the operator policies in the vhost.
+
+Operator policies are applied on top of the user policies, and can only limit
+the keys ` + strings.Join(operatorPolicyKeyNames(), ", ") + `.`,
+	Run: nil,
+}
+
+var operatorPolicyApplyTo = []string{"queues", "classic_queues", "quorum_queues", "streams"}
+
+// addPolicyCommands adds the set, delete and list commands to the policy or operator-policy command
+func addPolicyCommands(parent *cobra.Command, operator bool) {
+	kind, applyTo := "policy", policyApplyTo
+	if operator {
+		kind, applyTo = "operator policy", operatorPolicyApplyTo
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set",
+		Short: "Creates or updates the " + kind + " with the given --name",
+		Long: `Creates or updates the ` + kind + ` with the given --name.
+
+The definition keys are set with their dedicated flags, or with --definition key=value.
+The keys and values are checked against a catalogue of the keys known to RabbitMQ,
+so a misspelled key is reported instead of being silently ignored by RabbitMQ.
+Use --allow-unknown-keys for keys that are not in the catalogue, like keys added by plugins,
+their values can be typed with the key=value:type format used by --arg.
+
+RabbitMQ evaluates the --pattern as a PCRE regular expression. The pattern is checked as a Go regular expression,
+which does not support all PCRE features, like the look-ahead in '^(?!amq\.).*', so a pattern that
+cannot be checked is sent as is, with a warning.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			name, _ := flags.GetString("name")
+			if len(name) == 0 {
+				return fmt.Errorf("--name ( or -n ) is a required parameter")
+			}
+			pattern, _ := flags.GetString("pattern")
+			if _, err := regexp.Compile(pattern); err != nil {
+				// RabbitMQ evaluates the pattern as PCRE, which supports more than Go regular expressions
+				fmt.Fprintf(os.Stderr, "warning: the --pattern '%s' is not checked, it is not a valid Go regular expression: %v\n", pattern, err)
+			}
+			if a, _ := flags.GetString("apply-to"); !contains(applyTo, a) {
+				return fmt.Errorf("invalid --apply-to value '%s', use one of %s", a, strings.Join(applyTo, ", "))
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			definition, err := policyDefinition(cmd, operator)
+			if err != nil {
+				return err
+			}
+
+			cmd.SilenceUsage = true
+
+			flags := cmd.Flags()
+			name, _ := flags.GetString("name")
+			pattern, _ := flags.GetString("pattern")
+			priority, _ := flags.GetInt("priority")
+			a, _ := flags.GetString("apply-to")
+
+			var req api.Builder
+			if operator {
+				req = api.PutOperatorPolicyForVhost(api.Config.VHost, name)
+			} else {
+				req = api.PutPolicyForVhost(api.Config.VHost, name)
+			}
+			req.Body(map[string]interface{}{
+				"pattern":    pattern,
+				"definition": definition,
+				"priority":   priority,
+				"apply-to":   a,
+			})
+			if _, err := execute(req, nil); err != nil {
+				return err
+			}
+			fmt.Printf("%s '%s' set in vhost '%s' with definition %s\n", kind, name, api.Config.VHost, formatArguments(definition))
+			return nil
+		},
+	}
+
+	flags := setCmd.PersistentFlags()
+	flags.StringP("name", "n", "", "The "+kind+" name")
+	flags.String("pattern", "", "The regular expression for the names of the queues and/or exchanges the "+kind+" applies to")
+	flags.String("apply-to", applyTo[0], "The kind of objects the "+kind+" applies to: "+strings.Join(applyTo, ", "))
+	flags.Int("priority", 0, "The priority of the "+kind+", the "+kind+" with the highest priority applies when more "+kind+"s match")
+	flags.StringArray("definition", []string{}, "A definition key=value, can be repeated")
+	flags.Bool("allow-unknown-keys", false, "Accept definition keys that are not in the catalogue of known keys")
+	for _, k := range policyKeys {
+		if operator && !k.Operator {
+			continue
+		}
+		description := k.Description
+		if k.Type == "enum" {
+			description += ": " + strings.Join(k.Values, ", ")
+		}
+		if k.Type == "int" {
+			flags.Int64(k.Name, 0, description)
+		} else {
+			flags.String(k.Name, "", description)
+		}
+	}
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Deletes the " + kind + " with the given --name",
+		Long:  `Deletes the ` + kind + ` with the given --name`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if name, _ := cmd.Flags().GetString("name"); len(name) == 0 {
+				return fmt.Errorf("--name ( or -n ) is a required parameter")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			name, _ := cmd.Flags().GetString("name")
+			var req api.Builder
+			if operator {
+				req = api.DeleteOperatorPolicyForVhost(api.Config.VHost, name)
+			} else {
+				req = api.DeletePolicyForVhost(api.Config.VHost, name)
+			}
+			if _, err := execute(req, nil); err != nil {
+				if api.IsStatus(err, http.StatusNotFound) {
+					return fmt.Errorf("%s '%s' not found in vhost '%s'", kind, name, api.Config.VHost)
+				}
+				return err
+			}
+			fmt.Printf("%s '%s' deleted from vhost '%s'\n", kind, name, api.Config.VHost)
+			return nil
+		},
+	}
+	deleteCmd.PersistentFlags().StringP("name", "n", "", "The "+kind+" name")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Lists the " + kind + "s in the vhost",
+		Long:  `Lists the ` + kind + `s in the vhost`,
+		RunE: RunE(func(cmd *cobra.Command, args []string) (api.Builder, error) {
+			if operator {
+				return api.GetOperatorPoliciesForVhost(api.Config.VHost), nil
+			}
+			return api.GetPoliciesForVhost(api.Config.VHost), nil
+		}),
+	}
+
+	parent.AddCommand(setCmd)
+	parent.AddCommand(deleteCmd)
+	parent.AddCommand(listCmd)
+}
+
+// policyDefinition collects and validates the definition from the --definition flags and the flags of the catalogue keys
+func policyDefinition(cmd *cobra.Command, operator bool) (map[string]interface{}, error) {
+	flags := cmd.Flags()
+	allowUnknown, _ := flags.GetBool("allow-unknown-keys")
+	values, _ := flags.GetStringArray("definition")
+
+	definition := map[string]interface{}{}
+	for _, value := range values {
+		i := strings.Index(value, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("invalid definition '%s', use the key=value format", value)
+		}
+
+		key := value[:i]
+		k := findPolicyKey(key)
+		if k == nil {
+			if !allowUnknown {
+				// reports the unknown key with a suggestion
+				return nil, validatePolicyDefinition(map[string]interface{}{key: value[i+1:]}, operator, false)
+			}
+			_, v, err := parseArgument(value)
+			if err != nil {
+				return nil, err
+			}
+			definition[key] = v
+			continue
+		}
+
+		v, err := k.parse(value[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		definition[key] = v
+	}
+
+	for _, k := range policyKeys {
+		if !flags.Changed(k.Name) {
+			continue
+		}
+		if k.Type == "int" {
+			definition[k.Name], _ = flags.GetInt64(k.Name)
+			continue
+		}
+		s, _ := flags.GetString(k.Name)
+		v, err := k.parse(s)
+		if err != nil {
+			return nil, err
+		}
+		definition[k.Name] = v
+	}
+
+	if len(definition) == 0 {
+		return nil, fmt.Errorf("the definition is empty, set at least one definition key")
+	}
+	return definition, validatePolicyDefinition(definition, operator, allowUnknown)
+}
+
+func init() {
+	rootCmd.AddCommand(policyCmd)
+	addPolicyCommands(policyCmd, false)
+
+	rootCmd.AddCommand(operatorPolicyCmd)
+	addPolicyCommands(operatorPolicyCmd, true)
+}
@@ -0,0 +1,210 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// policyKey describes a policy definition key known to RabbitMQ, and the type of its value
+	policyKey struct {
+		Name        string
+		Type        string // int, string, enum or any
+		Values      []string
+		Operator    bool // the key can be used in operator policies
+		Description string
+	}
+)
+
+// policyKeys is the catalogue of known policy definition keys
+var policyKeys = []*policyKey{
+	{Name: "alternate-exchange", Type: "string", Description: "The exchange to route messages to that cannot be routed by the exchange"},
+	{Name: "consumer-timeout", Type: "int", Description: "The time in milliseconds a consumer can take to acknowledge a delivery before its channel is closed"},
+	{Name: "dead-letter-exchange", Type: "string", Description: "The exchange to dead-letter messages to"},
+	{Name: "dead-letter-routing-key", Type: "string", Description: "The routing key to use when dead-lettering messages"},
+	{Name: "dead-letter-strategy", Type: "enum", Values: []string{"at-most-once", "at-least-once"}, Description: "The dead-lettering strategy of quorum queues"},
+	{Name: "delivery-limit", Type: "int", Operator: true, Description: "The number of redeliveries before a message is dropped or dead-lettered, quorum queues only"},
+	{Name: "expires", Type: "int", Operator: true, Description: "The time in milliseconds a queue can be unused before it is deleted"},
+	{Name: "federation-upstream", Type: "string", Description: "The federation upstream to use"},
+	{Name: "federation-upstream-set", Type: "string", Description: "The federation upstream set to use, 'all' for all upstreams"},
+	{Name: "ha-mode", Type: "enum", Values: []string{"all", "exactly", "nodes"}, Description: "Deprecated classic queue mirroring mode"},
+	{Name: "ha-params", Type: "any", Description: "Deprecated classic queue mirroring parameter, a mirror count for ha-mode exactly, or a json list of node names for ha-mode nodes"},
+	{Name: "ha-promote-on-failure", Type: "enum", Values: []string{"always", "when-synced"}, Description: "Deprecated classic queue mirror promotion on failure"},
+	{Name: "ha-promote-on-shutdown", Type: "enum", Values: []string{"always", "when-synced"}, Description: "Deprecated classic queue mirror promotion on shutdown"},
+	{Name: "ha-sync-batch-size", Type: "int", Description: "Deprecated classic queue mirror synchronisation batch size"},
+	{Name: "ha-sync-mode", Type: "enum", Values: []string{"manual", "automatic"}, Description: "Deprecated classic queue mirror synchronisation mode"},
+	{Name: "initial-cluster-size", Type: "int", Description: "The number of replicas of a new quorum queue or stream"},
+	{Name: "max-age", Type: "string", Description: "The maximum age of the messages in a stream, e.g. 7D, 12h"},
+	{Name: "max-in-memory-bytes", Type: "int", Operator: true, Description: "The maximum total size in bytes of the messages a quorum queue keeps in memory"},
+	{Name: "max-in-memory-length", Type: "int", Operator: true, Description: "The maximum number of messages a quorum queue keeps in memory"},
+	{Name: "max-length", Type: "int", Operator: true, Description: "The maximum number of ready messages in the queue"},
+	{Name: "max-length-bytes", Type: "int", Operator: true, Description: "The maximum total size in bytes of the ready messages in the queue"},
+	{Name: "message-ttl", Type: "int", Operator: true, Description: "The time in milliseconds a message can stay in the queue before it is discarded or dead-lettered"},
+	{Name: "overflow", Type: "enum", Values: []string{"drop-head", "reject-publish", "reject-publish-dlx"}, Description: "The behaviour when the queue is full"},
+	{Name: "queue-leader-locator", Type: "enum", Values: []string{"client-local", "balanced"}, Description: "How the node of the queue leader is chosen"},
+	{Name: "queue-master-locator", Type: "enum", Values: []string{"min-masters", "client-local", "random"}, Description: "How the node of the classic queue master is chosen"},
+	{Name: "queue-mode", Type: "enum", Values: []string{"default", "lazy"}, Description: "The classic queue mode"},
+	{Name: "queue-version", Type: "int", Description: "The classic queue storage version"},
+	{Name: "stream-max-segment-size-bytes", Type: "int", Description: "The maximum size in bytes of the stream segment files"},
+	{Name: "target-group-size", Type: "int", Description: "The number of replicas the members of a quorum queue are grown to"},
+}
+
+var policyApplyTo = []string{"queues", "exchanges", "all", "classic_queues", "quorum_queues", "streams"}
+
+// findPolicyKey returns the catalogue entry for the policy definition key, or nil for an unknown key
+func findPolicyKey(name string) *policyKey {
+	for _, k := range policyKeys {
+		if k.Name == name {
+			return k
+		}
+	}
+	return nil
+}
+
+// operatorPolicyKeyNames returns the names of the keys that can be used in operator policies
+func operatorPolicyKeyNames() []string {
+	names := []string{}
+	for _, k := range policyKeys {
+		if k.Operator {
+			names = append(names, k.Name)
+		}
+	}
+	return names
+}
+
+// parse converts a commandline value to the type of the key
+func (k *policyKey) parse(value string) (interface{}, error) {
+	var v interface{} = value
+	switch k.Type {
+	case "int":
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("policy definition key '%s' requires an integer value, got '%s'", k.Name, value)
+		}
+		v = i
+	case "any":
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			v = value
+		}
+	}
+	return v, k.check(v)
+}
+
+// check returns an error when the value does not have the type of the key
+func (k *policyKey) check(value interface{}) error {
+	switch k.Type {
+	case "int":
+		switch n := value.(type) {
+		case int, int64:
+			return nil
+		case float64:
+			if n == math.Trunc(n) {
+				return nil
+			}
+		}
+		return fmt.Errorf("policy definition key '%s' requires an integer value, got %s", k.Name, formatValue(value))
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("policy definition key '%s' requires a string value, got %s", k.Name, formatValue(value))
+		}
+	case "enum":
+		s, _ := value.(string)
+		if !contains(k.Values, s) {
+			return fmt.Errorf("policy definition key '%s' requires one of %s, got %s", k.Name, strings.Join(k.Values, ", "), formatValue(value))
+		}
+	}
+	return nil
+}
+
+// validatePolicyDefinition checks the definition keys against the catalogue, and the values against the types of the keys.
+// Unknown keys are reported with a suggestion for the key that was probably meant, unless allowUnknown is true.
+// For operator policies, only the keys that are allowed in operator policies are accepted.
+func validatePolicyDefinition(definition map[string]interface{}, operator, allowUnknown bool) error {
+	names := []string{}
+	for name := range definition {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		k := findPolicyKey(name)
+		if k == nil {
+			if allowUnknown {
+				continue
+			}
+			msg := fmt.Sprintf("unknown policy definition key '%s'", name)
+			if suggestion := suggestPolicyKey(name); len(suggestion) > 0 {
+				msg += fmt.Sprintf(", did you mean '%s'?", suggestion)
+			}
+			return fmt.Errorf("%s", msg)
+		}
+		if operator && !k.Operator {
+			return fmt.Errorf("policy definition key '%s' is not allowed in operator policies, use one of %s", name, strings.Join(operatorPolicyKeyNames(), ", "))
+		}
+		if err := k.check(definition[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// suggestPolicyKey returns the known key closest to name, or an empty string when no key is close enough
+func suggestPolicyKey(name string) string {
+	best, bestDistance := "", 3
+	for _, k := range policyKeys {
+		if d := editDistance(name, k.Name); d < bestDistance {
+			best, bestDistance = k.Name, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_validatePolicyDefinition(t *testing.T) {
+	valid := map[string]interface{}{
+		"max-length":           float64(1000),
+		"overflow":             "reject-publish",
+		"dead-letter-exchange": "dlx",
+		"ha-params":            []interface{}{"rabbit@a", "rabbit@b"},
+	}
+	if err := validatePolicyDefinition(valid, false, false); err != nil {
+		t.Errorf("Expected a valid definition, got %v", err)
+	}
+
+	tests := []struct {
+		definition map[string]interface{}
+		operator   bool
+		expected   string
+	}{
+		{map[string]interface{}{"max-lenght": 10}, false, "did you mean 'max-length'"},
+		{map[string]interface{}{"max-length": "10"}, false, "requires an integer value"},
+		{map[string]interface{}{"max-length": 10.5}, false, "requires an integer value"},
+		{map[string]interface{}{"overflow": "drop"}, false, "requires one of drop-head"},
+		{map[string]interface{}{"dead-letter-exchange": "dlx"}, true, "not allowed in operator policies"},
+		{map[string]interface{}{"completely-unknown": 1}, false, "unknown policy definition key 'completely-unknown'"},
+	}
+
+	for _, test := range tests {
+		err := validatePolicyDefinition(test.definition, test.operator, false)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("validatePolicyDefinition(%v) = %v, expected an error containing '%s'", test.definition, err, test.expected)
+		}
+	}
+
+	if err := validatePolicyDefinition(map[string]interface{}{"x-plugin-key": 1}, false, true); err != nil {
+		t.Errorf("Expected unknown keys to be allowed, got %v", err)
+	}
+}
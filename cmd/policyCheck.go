@@ -0,0 +1,328 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/LogiqsAgro/rmq/api/vhost"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// policyCheckCmd represents the policy-check command
+var policyCheckCmd = &cobra.Command{
+	Use:   "policy-check [live]",
+	Short: "Checks the definitions of a --file, or of the live broker, against the governance rules in the config file",
+	Long: `Checks the definitions of a JSON or YAML --file, or the live definitions of all vhosts, or only of
+the vhost when --vhost is given, against the governance rules in the config file:
+
+  policy-check:
+    rules:
+      - name: queue-naming
+        description: queue names follow the <domain>.<name>.v<version> convention
+        applies-to: queues
+        assert: name =~ "^[a-z]+\.[a-z-]+\.v\d+$"
+      - name: max-length-on-transient-queues
+        applies-to: queues
+        severity: warning
+        when: "!durable"
+        assert: has(arguments.x-max-length) || has(policy.max-length)
+
+A rule applies-to queues, exchanges, bindings, policies, users, vhosts, permissions, topic-permissions,
+parameters or global-parameters. The assert expression is evaluated for each object the rule applies to,
+and for which the optional when expression is true. Objects for which the assert is false are reported
+with the severity of the rule: error (the default), warning or info.
+
+The expressions use the fields of the objects, as in the definitions, e.g. name, durable or arguments.x-max-length.
+The type of a queue is its queue type (classic, quorum or stream), even when it is set by the x-queue-type
+argument. Queues and exchanges have a policy field, with the definition of the policy that applies to them.
+RabbitMQ evaluates policy patterns as PCRE regular expressions, they are evaluated here as Go regular
+expressions, which do not support all PCRE features, like the look-ahead in '^(?!amq\.).*'. Policies with
+such a pattern are ignored for the policy field, and reported as a warning with the unevaluated-policy-pattern rule.
+
+  literals     "text", 'text', 42, 1.5, true, false, null
+  comparison   ==, !=, <, <=, >, >=
+  matching     =~ "regex", !~ "regex"
+  logic        &&, ||, !, ( )
+  functions    has(path), true when the field exists and is not null
+
+A backslash in a string only escapes the quote and a backslash, so regular expressions can be written as is.
+The --format is human or json. The command exits with exit code 1 when there are violations with the
+--fail-on severity or higher.`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateDefinitionSource(args, policyCheckFile); err != nil {
+			return err
+		}
+		if !contains(lintFormats, policyCheckFormat) {
+			return fmt.Errorf("invalid --format '%s', expected one of %s", policyCheckFormat, strings.Join(lintFormats, ", "))
+		}
+		if _, ok := severityRanks[policyCheckFailOn]; !ok {
+			return fmt.Errorf("invalid --fail-on '%s', expected one of %s", policyCheckFailOn, strings.Join(severities, ", "))
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		rules, err := readGovernanceRules()
+		if err != nil {
+			return err
+		}
+
+		definition, vhostName, err := readDefinitionSource(cmd, policyCheckFile)
+		if err != nil {
+			return err
+		}
+
+		findings := checkGovernanceRules(definition, vhostName, rules)
+		return reportFindings(findings, policyCheckFormat, policyCheckFailOn)
+	},
+}
+
+// governanceRule is a rule from the policy-check.rules in the config file
+type governanceRule struct {
+	Name        string `mapstructure:"name"`
+	Description string `mapstructure:"description"`
+	AppliesTo   string `mapstructure:"applies-to"`
+	Severity    string `mapstructure:"severity"`
+	When        string `mapstructure:"when"`
+	Assert      string `mapstructure:"assert"`
+
+	when   ruleExpression
+	assert ruleExpression
+}
+
+// governanceObject is an object in the definitions, with the fields the rule expressions are evaluated against
+type governanceObject struct {
+	Type   string
+	VHost  string
+	Name   string
+	Fields map[string]interface{}
+}
+
+var governanceTargets = []string{
+	"queues", "exchanges", "bindings", "policies", "users", "vhosts",
+	"permissions", "topic-permissions", "parameters", "global-parameters",
+}
+
+// readGovernanceRules reads and compiles the rules in the config file
+func readGovernanceRules() ([]*governanceRule, error) {
+	rules := []*governanceRule{}
+	if err := viper.UnmarshalKey("policy-check.rules", &rules); err != nil {
+		return nil, fmt.Errorf("invalid policy-check.rules in the config file: %v", err)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no policy-check.rules found in the config file")
+	}
+	if err := compileGovernanceRules(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// compileGovernanceRules validates the rules, and compiles their expressions
+func compileGovernanceRules(rules []*governanceRule) error {
+	names := map[string]bool{}
+	for i, r := range rules {
+		if len(r.Name) == 0 {
+			return fmt.Errorf("policy-check rule %d has no name", i+1)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("policy-check rule '%s' is defined more than once", r.Name)
+		}
+		names[r.Name] = true
+
+		if !contains(governanceTargets, r.AppliesTo) {
+			return fmt.Errorf("policy-check rule '%s' has an invalid applies-to '%s', expected one of %s", r.Name, r.AppliesTo, strings.Join(governanceTargets, ", "))
+		}
+		if len(r.Severity) == 0 {
+			r.Severity = severityError
+		}
+		if _, ok := severityRanks[r.Severity]; !ok {
+			return fmt.Errorf("policy-check rule '%s' has an invalid severity '%s', expected one of %s", r.Name, r.Severity, strings.Join(severities, ", "))
+		}
+		if len(strings.TrimSpace(r.Assert)) == 0 {
+			return fmt.Errorf("policy-check rule '%s' has no assert expression", r.Name)
+		}
+
+		var err error
+		if r.assert, err = parseRule(r.Assert); err != nil {
+			return fmt.Errorf("policy-check rule '%s' has an %v", r.Name, err)
+		}
+		if len(strings.TrimSpace(r.When)) > 0 {
+			if r.when, err = parseRule(r.When); err != nil {
+				return fmt.Errorf("policy-check rule '%s' has an %v", r.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkGovernanceRules returns a finding for each object that violates a rule
+func checkGovernanceRules(d *vhost.Definition, vhostName string, rules []*governanceRule) []*finding {
+	objects := map[string][]*governanceObject{}
+	findings := []*finding{}
+	usesPolicyField := false
+	for _, r := range rules {
+		usesPolicyField = usesPolicyField || r.AppliesTo == "queues" || r.AppliesTo == "exchanges"
+		if _, ok := objects[r.AppliesTo]; !ok {
+			objects[r.AppliesTo] = governanceObjects(d, vhostName, r.AppliesTo)
+		}
+		for _, o := range objects[r.AppliesTo] {
+			if r.when != nil && !truthy(r.when.eval(o.Fields)) {
+				continue
+			}
+			if truthy(r.assert.eval(o.Fields)) {
+				continue
+			}
+			message := r.Description
+			if len(message) == 0 {
+				message = "violates " + r.Assert
+			}
+			findings = append(findings, &finding{
+				Rule:     r.Name,
+				Severity: r.Severity,
+				Type:     o.Type,
+				VHost:    o.VHost,
+				Name:     o.Name,
+				Message:  message,
+			})
+		}
+	}
+	if usesPolicyField {
+		findings = append(findings, unevaluatedPolicyPatterns(d, vhostName)...)
+	}
+	sortFindings(findings)
+	return findings
+}
+
+// unevaluatedPolicyPatterns returns a warning for each policy with a pattern that is not a valid Go regular
+// expression, as these policies are ignored for the policy field of queues and exchanges
+func unevaluatedPolicyPatterns(d *vhost.Definition, vhostName string) []*finding {
+	findings := []*finding{}
+	for _, p := range d.Policies {
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			findings = append(findings, &finding{
+				Rule:     "unevaluated-policy-pattern",
+				Severity: severityWarning,
+				Type:     "policy",
+				VHost:    objectVHost(vhostName, p.VHost),
+				Name:     p.Name,
+				Message:  fmt.Sprintf("cannot evaluate pattern '%s', the policy is ignored for the policy field: %v", p.Pattern, err),
+			})
+		}
+	}
+	return findings
+}
+
+// governanceObjects returns the objects of the target in the definitions
+func governanceObjects(d *vhost.Definition, vhostName, target string) []*governanceObject {
+	objects := []*governanceObject{}
+	add := func(kind, name string, value interface{}) map[string]interface{} {
+		fields := map[string]interface{}{}
+		data, _ := json.Marshal(value)
+		json.Unmarshal(data, &fields)
+		objects = append(objects, &governanceObject{Type: kind, Name: name, Fields: fields})
+		return fields
+	}
+	// the objects of per-vhost definitions have no vhost, so it is set for all objects in a vhost
+	addInVHost := func(kind, objectVhost, name string, value interface{}) map[string]interface{} {
+		fields := add(kind, name, value)
+		fields["vhost"] = objectVHost(vhostName, objectVhost)
+		objects[len(objects)-1].VHost = objectVHost(vhostName, objectVhost)
+		return fields
+	}
+
+	switch target {
+	case "queues":
+		for _, q := range d.Queues {
+			fields := addInVHost("queue", q.VHost, q.Name, q)
+			fields["type"] = queueType(q)
+			if p := effectivePolicy(d, vhostName, q.VHost, "queue", queueType(q), q.Name); p != nil {
+				fields["policy"] = p.Definition.Map()
+			}
+		}
+	case "exchanges":
+		for _, e := range d.Exchanges {
+			fields := addInVHost("exchange", e.VHost, e.Name, e)
+			if p := effectivePolicy(d, vhostName, e.VHost, "exchange", "", e.Name); p != nil {
+				fields["policy"] = p.Definition.Map()
+			}
+		}
+	case "bindings":
+		for _, b := range d.Bindings {
+			addInVHost("binding", b.VHost, b.Name(), b)
+		}
+	case "policies":
+		for _, p := range d.Policies {
+			addInVHost("policy", p.VHost, p.Name, p)
+		}
+	case "users":
+		for _, u := range d.Users {
+			fields := add("user", u.Name, u)
+			fields["tags"] = stringsToValues(u.Tags.Values)
+		}
+	case "vhosts":
+		for _, v := range d.VHosts {
+			add("vhost", v.Name, v)
+		}
+	case "permissions":
+		for _, p := range d.Permissions {
+			addInVHost("permission", p.VHost, p.User, p)
+		}
+	case "topic-permissions":
+		for _, p := range d.TopicPermissions {
+			addInVHost("topic permission", p.VHost, p.User+" "+p.Exchange, p)
+		}
+	case "parameters":
+		for _, p := range d.Parameters {
+			addInVHost(p.Component, p.VHost, p.Name, p)
+		}
+	case "global-parameters":
+		for _, p := range d.GlobalParameters {
+			add("global parameter", p.Name, p)
+		}
+	}
+	return objects
+}
+
+// stringsToValues returns the strings as generic values, like decoded JSON
+func stringsToValues(values []string) []interface{} {
+	result := []interface{}{}
+	for _, v := range values {
+		result = append(result, v)
+	}
+	return result
+}
+
+var (
+	policyCheckFile   string
+	policyCheckFormat string
+	policyCheckFailOn string
+)
+
+func init() {
+	rootCmd.AddCommand(policyCheckCmd)
+	flags := policyCheckCmd.PersistentFlags()
+	flags.StringVarP(&policyCheckFile, "file", "f", "", "The JSON or YAML definitions file to check, instead of the live definitions")
+	flags.StringVar(&policyCheckFormat, "format", "human", "The output format: "+strings.Join(lintFormats, ", "))
+	flags.StringVar(&policyCheckFailOn, "fail-on", severityError, "Exit with exit code 1 on violations with this severity or higher: "+strings.Join(severities, ", "))
+}
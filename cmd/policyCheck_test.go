@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/LogiqsAgro/rmq/api/vhost"
+)
+
+func Test_checkGovernanceRules(t *testing.T) {
+	rules := []*governanceRule{
+		{Name: "queue-naming", AppliesTo: "queues", Assert: `name =~ "^[a-z]+\.[a-z-]+\.v\d+$"`},
+		{Name: "quorum-queues", AppliesTo: "queues", Severity: "warning", Assert: `type == "quorum"`},
+		{Name: "dead-letter-exchange", AppliesTo: "queues", Assert: `has(arguments.x-dead-letter-exchange) || has(policy.dead-letter-exchange)`},
+		{Name: "max-length", AppliesTo: "queues", When: `!durable`, Assert: `has(arguments.x-max-length) || has(policy.max-length)`},
+		{Name: "user-tags", AppliesTo: "users", Severity: "info", Description: "users are not administrators", Assert: `name == "admin" || !has(tags)`},
+	}
+	if err := compileGovernanceRules(rules); err != nil {
+		t.Fatalf("compileGovernanceRules() failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		definition *vhost.Definition
+		vhostName  string
+		expected   []finding
+	}{
+		{
+			name: "queues are checked with their type and the policy that applies to them",
+			definition: &vhost.Definition{
+				Users: []*vhost.User{
+					{Name: "admin", Tags: vhost.UserTags{Values: []string{"administrator"}}},
+				},
+				Queues: []*vhost.Queue{
+					{VHost: "/", Name: "orders.created.v1", Durable: true, Arguments: map[string]interface{}{"x-queue-type": "quorum"}},
+					{VHost: "/", Name: "Orders", Durable: false, Arguments: map[string]interface{}{"x-dead-letter-exchange": "dlx"}},
+					{VHost: "/", Name: "orders.audit-log.v2", Durable: false, Type: "quorum"},
+				},
+				Policies: []*vhost.Policy{
+					{VHost: "/", Name: "dlx", Pattern: "^orders\\.", ApplyTo: "quorum_queues", Priority: 1, Definition: vhost.NewPolicyDefinition(map[string]interface{}{"dead-letter-exchange": "dlx"})},
+					{VHost: "/", Name: "limit", Pattern: "audit", ApplyTo: "queues", Priority: 0, Definition: vhost.NewPolicyDefinition(map[string]interface{}{"max-length": 10})},
+				},
+			},
+			expected: []finding{
+				{"max-length", "error", "queue", "/", "Orders", "violates has(arguments.x-max-length) || has(policy.max-length)"},
+				{"max-length", "error", "queue", "/", "orders.audit-log.v2", "violates has(arguments.x-max-length) || has(policy.max-length)"},
+				{"queue-naming", "error", "queue", "/", "Orders", `violates name =~ "^[a-z]+\.[a-z-]+\.v\d+$"`},
+				{"quorum-queues", "warning", "queue", "/", "Orders", `violates type == "quorum"`},
+			},
+		},
+		{
+			name: "policies only apply to queues in their own vhost",
+			definition: &vhost.Definition{
+				Users: []*vhost.User{
+					{Name: "guest", Tags: vhost.UserTags{Values: []string{"management"}}},
+				},
+				Queues: []*vhost.Queue{
+					{VHost: "a", Name: "orders.created.v1", Durable: true, Type: "quorum"},
+					{VHost: "b", Name: "orders.created.v1", Durable: true, Type: "quorum"},
+				},
+				Policies: []*vhost.Policy{
+					{VHost: "a", Name: "dlx", Pattern: "^orders\\.", Definition: vhost.NewPolicyDefinition(map[string]interface{}{"dead-letter-exchange": "dlx"})},
+				},
+			},
+			expected: []finding{
+				{"dead-letter-exchange", "error", "queue", "b", "orders.created.v1", "violates has(arguments.x-dead-letter-exchange) || has(policy.dead-letter-exchange)"},
+				{"user-tags", "info", "user", "", "guest", "users are not administrators"},
+			},
+		},
+		{
+			name:      "objects of per-vhost definitions are in the given vhost",
+			vhostName: "orders",
+			definition: &vhost.Definition{
+				Queues: []*vhost.Queue{
+					{Name: "orders.created.v1", Durable: true, Type: "quorum"},
+					{Name: "orders.shipped.v1", Durable: true, Type: "quorum"},
+				},
+				Policies: []*vhost.Policy{
+					{Name: "dlx", Pattern: "created", Definition: vhost.NewPolicyDefinition(map[string]interface{}{"dead-letter-exchange": "dlx"})},
+					{Name: "pcre", Pattern: `^(?!amq\.).*`, Definition: vhost.NewPolicyDefinition(map[string]interface{}{"dead-letter-exchange": "dlx"})},
+				},
+			},
+			expected: []finding{
+				{"dead-letter-exchange", "error", "queue", "orders", "orders.shipped.v1", "violates has(arguments.x-dead-letter-exchange) || has(policy.dead-letter-exchange)"},
+				{"unevaluated-policy-pattern", "warning", "policy", "orders", "pcre", "cannot evaluate pattern '^(?!amq\\.).*', the policy is ignored for the policy field: error parsing regexp: invalid or unsupported Perl syntax: `(?!`"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		actual := []finding{}
+		for _, f := range checkGovernanceRules(test.definition, test.vhostName, rules) {
+			actual = append(actual, *f)
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: checkGovernanceRules() = %+v, expected %+v", test.name, actual, test.expected)
+		}
+	}
+}
+
+func Test_compileGovernanceRules_Errors(t *testing.T) {
+	tests := [][]*governanceRule{
+		{{AppliesTo: "queues", Assert: "durable"}},
+		{{Name: "a", AppliesTo: "queues", Assert: "durable"}, {Name: "a", AppliesTo: "queues", Assert: "durable"}},
+		{{Name: "a", AppliesTo: "connections", Assert: "durable"}},
+		{{Name: "a", AppliesTo: "queues", Severity: "fatal", Assert: "durable"}},
+		{{Name: "a", AppliesTo: "queues"}},
+		{{Name: "a", AppliesTo: "queues", Assert: "durable &&"}},
+		{{Name: "a", AppliesTo: "queues", When: "(", Assert: "durable"}},
+	}
+	for i, rules := range tests {
+		if err := compileGovernanceRules(rules); err == nil {
+			t.Errorf("compileGovernanceRules() of test %d succeeded, expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// publishCmd represents the publish command
+var publishCmd = &cobra.Command{
+	Use:   "publish [payload]",
+	Short: "Publishes messages to an exchange",
+	Long: `Publishes a message to the --exchange with the --routing-key, the default exchange is used when no exchange is specified.
+
+The payload is taken from the argument, from the --file, or from stdin when neither is given.
+Payloads that are not valid UTF-8 are sent base64 encoded.
+Headers can be added with --header key=value:type, where type is one of string (the default), int, float, bool or json.
+
+With --batch, messages are read as newline delimited json records from the --file or stdin, like:
+  {"exchange":"orders","routing_key":"order.created","properties":{"content_type":"application/json"},"payload":"{\"id\":1}"}
+The exchange, routing_key and properties of a record default to the values of the flags.
+The payload can be any json value, strings are sent as-is, other values are sent as json.
+Use "payload_encoding":"base64" to send a base64 encoded binary payload.
+
+The command fails when any message could not be routed to a queue.`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 0 && (len(publishFile) > 0 || publishBatch) {
+			return fmt.Errorf("a payload argument cannot be combined with --file or --batch")
+		}
+		if cmd.Flags().Changed("delivery-mode") && publishDeliveryMode != 1 && publishDeliveryMode != 2 {
+			return fmt.Errorf("invalid delivery mode %d, use 1 (non-persistent) or 2 (persistent)", publishDeliveryMode)
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		properties, err := publishProperties(cmd)
+		if err != nil {
+			return err
+		}
+
+		cmd.SilenceUsage = true
+
+		in := io.Reader(os.Stdin)
+		if len(publishFile) > 0 && publishFile != "-" {
+			f, err := os.Open(publishFile)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+
+		if publishBatch {
+			return publishRecords(in, properties)
+		}
+
+		var payload []byte
+		if len(args) > 0 {
+			payload = []byte(args[0])
+		} else if payload, err = io.ReadAll(in); err != nil {
+			return err
+		}
+
+		m := &publishRecord{Properties: properties}
+		m.setPayload(payload)
+		routed, err := m.publish()
+		if err != nil {
+			return err
+		}
+		if !routed {
+			return fmt.Errorf("message published to exchange '%s' with routing key '%s' could not be routed to any queue", m.exchangeName(), m.routingKey())
+		}
+		fmt.Printf("message published to exchange '%s' with routing key '%s' and routed\n", m.exchangeName(), m.routingKey())
+		return nil
+	},
+}
+
+type (
+	// publishRecord is a message to publish, as read from a line in the --batch input
+	publishRecord struct {
+		Exchange        *string                `json:"exchange"`
+		RoutingKey      *string                `json:"routing_key"`
+		Properties      map[string]interface{} `json:"properties"`
+		Payload         json.RawMessage        `json:"payload"`
+		PayloadEncoding string                 `json:"payload_encoding"`
+	}
+)
+
+func (m *publishRecord) exchangeName() string {
+	if m.Exchange != nil {
+		return *m.Exchange
+	}
+	return publishExchange
+}
+
+func (m *publishRecord) routingKey() string {
+	if m.RoutingKey != nil {
+		return *m.RoutingKey
+	}
+	return publishRoutingKey
+}
+
+// setPayload sets the payload as a json string, base64 encoded if it is not valid UTF-8
+func (m *publishRecord) setPayload(payload []byte) {
+	m.PayloadEncoding = "string"
+	if !utf8.Valid(payload) {
+		m.PayloadEncoding = "base64"
+		payload = []byte(base64.StdEncoding.EncodeToString(payload))
+	}
+	m.Payload, _ = json.Marshal(string(payload))
+}
+
+// payload returns the payload string to send, non-string json payloads are sent as json
+func (m *publishRecord) payload() string {
+	s := ""
+	if err := json.Unmarshal(m.Payload, &s); err != nil {
+		return string(bytes.TrimSpace(m.Payload))
+	}
+	return s
+}
+
+// publish publishes the message and returns true if it was routed to at least one queue
+func (m *publishRecord) publish() (bool, error) {
+	exchange := m.exchangeName()
+	if len(exchange) == 0 {
+		exchange = "amq.default"
+	}
+
+	encoding := m.PayloadEncoding
+	if len(encoding) == 0 {
+		encoding = "string"
+	}
+
+	properties := m.Properties
+	if properties == nil {
+		properties = map[string]interface{}{}
+	}
+
+	req := api.PostExchangePublishForVhost(api.Config.VHost, exchange).Body(map[string]interface{}{
+		"properties":       properties,
+		"routing_key":      m.routingKey(),
+		"payload":          m.payload(),
+		"payload_encoding": encoding,
+	})
+
+	result := struct {
+		Routed bool `json:"routed"`
+	}{}
+	if _, err := execute(req, &result); err != nil {
+		return false, err
+	}
+	return result.Routed, nil
+}
+
+// publishRecords publishes the newline delimited json records read from in, and reports if each message was routed
+func publishRecords(in io.Reader, properties map[string]interface{}) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 128*1024*1024)
+
+	line, count, unroutable := 0, 0, 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 {
+			continue
+		}
+
+		m := &publishRecord{}
+		if err := json.Unmarshal([]byte(text), m); err != nil {
+			return fmt.Errorf("invalid record on line %d: %v", line, err)
+		}
+		for k, v := range properties {
+			if _, ok := m.Properties[k]; !ok {
+				if m.Properties == nil {
+					m.Properties = map[string]interface{}{}
+				}
+				m.Properties[k] = v
+			}
+		}
+
+		count++
+		routed, err := m.publish()
+		if err != nil {
+			return fmt.Errorf("failed to publish the record on line %d: %v", line, err)
+		}
+		if !routed {
+			unroutable++
+		}
+		fmt.Printf("line %d: exchange '%s', routing key '%s', routed: %v\n", line, m.exchangeName(), m.routingKey(), routed)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if unroutable > 0 {
+		return fmt.Errorf("%d of %d message(s) could not be routed to any queue", unroutable, count)
+	}
+	return nil
+}
+
+// publishProperties collects the message properties and headers from the flags
+func publishProperties(cmd *cobra.Command) (map[string]interface{}, error) {
+	properties := map[string]interface{}{}
+	flags := cmd.Flags()
+
+	strs := map[string]*string{
+		"content-type":     &publishContentType,
+		"content-encoding": &publishContentEncoding,
+		"correlation-id":   &publishCorrelationId,
+		"reply-to":         &publishReplyTo,
+		"expiration":       &publishExpiration,
+		"message-id":       &publishMessageId,
+		"type":             &publishType,
+		"user-id":          &publishUserId,
+		"app-id":           &publishAppId,
+	}
+	for name, value := range strs {
+		if flags.Changed(name) {
+			properties[strings.ReplaceAll(name, "-", "_")] = *value
+		}
+	}
+
+	if flags.Changed("priority") {
+		properties["priority"] = publishPriority
+	}
+	if flags.Changed("delivery-mode") {
+		properties["delivery_mode"] = publishDeliveryMode
+	}
+	if flags.Changed("timestamp") {
+		ts, err := parseTimestamp(publishTimestamp)
+		if err != nil {
+			return nil, err
+		}
+		properties["timestamp"] = ts
+	}
+
+	if len(publishHeaders) > 0 {
+		headers, err := parseArguments(publishHeaders)
+		if err != nil {
+			return nil, err
+		}
+		properties["headers"] = headers
+	}
+	return properties, nil
+}
+
+// parseTimestamp parses 'now', a unix timestamp in seconds or an RFC3339 time into a unix timestamp in seconds
+func parseTimestamp(s string) (int64, error) {
+	if s == "now" {
+		return time.Now().Unix(), nil
+	}
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ts, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp '%s', use 'now', a unix timestamp in seconds or an RFC3339 time like 2006-01-02T15:04:05Z", s)
+	}
+	return t.Unix(), nil
+}
+
+var (
+	publishExchange        string
+	publishRoutingKey      string
+	publishFile            string
+	publishBatch           bool
+	publishContentType     string
+	publishContentEncoding string
+	publishCorrelationId   string
+	publishReplyTo         string
+	publishExpiration      string
+	publishMessageId       string
+	publishPriority        int
+	publishDeliveryMode    int
+	publishTimestamp       string
+	publishType            string
+	publishUserId          string
+	publishAppId           string
+	publishHeaders         []string
+)
+
+func init() {
+	rootCmd.AddCommand(publishCmd)
+	flags := publishCmd.PersistentFlags()
+	flags.StringVarP(&publishExchange, "exchange", "e", "", "The exchange to publish to, the default exchange is used when not specified")
+	flags.StringVarP(&publishRoutingKey, "routing-key", "k", "", "The routing key")
+	flags.StringVarP(&publishFile, "file", "f", "", "The file to read the payload, or the --batch records from, use - for stdin")
+	flags.BoolVarP(&publishBatch, "batch", "b", false, "Publish the newline delimited json records read from --file or stdin")
+	flags.StringVar(&publishContentType, "content-type", "", "The content_type property, e.g. application/json")
+	flags.StringVar(&publishContentEncoding, "content-encoding", "", "The content_encoding property, e.g. gzip")
+	flags.StringVar(&publishCorrelationId, "correlation-id", "", "The correlation_id property")
+	flags.StringVar(&publishReplyTo, "reply-to", "", "The reply_to property")
+	flags.StringVar(&publishExpiration, "expiration", "", "The expiration property, the message TTL in milliseconds")
+	flags.StringVar(&publishMessageId, "message-id", "", "The message_id property")
+	flags.IntVar(&publishPriority, "priority", 0, "The priority property")
+	flags.IntVar(&publishDeliveryMode, "delivery-mode", 0, "The delivery_mode property, 1 (non-persistent) or 2 (persistent)")
+	flags.StringVar(&publishTimestamp, "timestamp", "", "The timestamp property, 'now', a unix timestamp in seconds or an RFC3339 time")
+	flags.StringVar(&publishType, "type", "", "The type property")
+	flags.StringVar(&publishUserId, "user-id", "", "The user_id property, must match the user name used to publish")
+	flags.StringVar(&publishAppId, "app-id", "", "The app_id property")
+	flags.StringArrayVar(&publishHeaders, "header", []string{}, "Message header in the key=value:type format, type is one of "+strings.Join(argumentTypes, ", ")+", can be repeated")
+}
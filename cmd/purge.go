@@ -0,0 +1,95 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// purgeCmd represents the purge command
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Purges the ready messages from the queue with the given --name, or from all queues matching the --name regex when --regex is used",
+	Long: `Purges the ready messages from the queue with the given --name, or from all queues matching the --name regex when --regex is used.
+
+The matching queues are listed with their ready and unacknowledged message counts,
+and you are asked for confirmation before they are purged, unless --yes is used.
+
+Unacknowledged messages are not purged, they are still owned by a consumer.
+The purged counts are based on the queue statistics before the purge, which
+are updated periodically by RabbitMQ, so they can be slightly out of date.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(api.Page.Name) == 0 {
+			return fmt.Errorf("--name ( or -n ) is a required parameter")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		vhost := api.Config.VHost
+		queues, err := selectQueues(vhost)
+		if err != nil {
+			return err
+		}
+		if len(queues) == 0 {
+			fmt.Printf("no queues in vhost '%s' match '%s'\n", vhost, api.Page.Name)
+			return nil
+		}
+
+		rows := [][]string{}
+		for _, q := range queues {
+			rows = append(rows, []string{q.Name, fmt.Sprint(q.MessagesReady), fmt.Sprint(q.MessagesUnacknowledged)})
+		}
+		printTable([]string{"NAME", "READY", "UNACKED"}, rows)
+
+		if !purgeYes {
+			ok, err := confirm(fmt.Sprintf("Purge %d queue(s) in vhost '%s'?", len(queues), vhost))
+			if err != nil || !ok {
+				return err
+			}
+		}
+
+		failed := 0
+		rows = [][]string{}
+		for _, q := range queues {
+			if _, err := execute(api.DeleteQueueContentsForVhost(vhost, q.Name), nil); err != nil {
+				failed++
+				rows = append(rows, []string{q.Name, "failed: " + errorReason(err), fmt.Sprint(q.MessagesUnacknowledged)})
+				continue
+			}
+			rows = append(rows, []string{q.Name, fmt.Sprint(q.MessagesReady), fmt.Sprint(q.MessagesUnacknowledged)})
+		}
+		fmt.Println()
+		printTable([]string{"NAME", "PURGED", "UNACKED"}, rows)
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d queue(s) could not be purged", failed, len(queues))
+		}
+		return nil
+	},
+}
+
+var purgeYes bool
+
+func init() {
+	rootCmd.AddCommand(purgeCmd)
+	api.AddPagingFlags(purgeCmd)
+	purgeCmd.PersistentFlags().BoolVarP(&purgeYes, "yes", "y", false, "Purge without asking for confirmation")
+}
@@ -0,0 +1,216 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// queueSyncCmd represents the queue sync command
+var queueSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Synchronises the mirrors of the classic mirrored queue with the given --name, or of all queues matching the --name regex when --regex is used",
+	Long: `Synchronises the mirrors of the classic mirrored queue with the given --name, or of all queues matching the --name regex when --regex is used.
+
+A sync is only started for queues with unsynchronised mirrors. Note that a queue is unavailable while it is synchronising.
+With --wait, the progress is shown every --interval until all mirrors are synchronised, or the --timeout expires.`,
+	PreRunE: validateQueueSyncFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		vhost := api.Config.VHost
+		queues, err := unsynchronisedQueues(vhost)
+		if err != nil || len(queues) == 0 {
+			return err
+		}
+
+		if queueSyncDryRun {
+			fmt.Printf("dry run: a sync would be started for %d queue(s) in vhost '%s'\n", len(queues), vhost)
+			return nil
+		}
+
+		if err := postQueueActions(vhost, queues, "sync"); err != nil {
+			return err
+		}
+		if !queueSyncWait {
+			return nil
+		}
+		return waitForQueueSync(vhost, queues)
+	},
+}
+
+// queueCancelSyncCmd represents the queue cancel-sync command
+var queueCancelSyncCmd = &cobra.Command{
+	Use:   "cancel-sync",
+	Short: "Cancels the synchronisation of the mirrors of the classic mirrored queue with the given --name, or of all queues matching the --name regex when --regex is used",
+	Long: `Cancels the synchronisation of the mirrors of the classic mirrored queue with the given --name, or of all queues matching the --name regex when --regex is used.
+
+The sync is only cancelled for queues with unsynchronised mirrors.`,
+	PreRunE: validateQueueSyncFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		vhost := api.Config.VHost
+		queues, err := unsynchronisedQueues(vhost)
+		if err != nil || len(queues) == 0 {
+			return err
+		}
+
+		if queueSyncDryRun {
+			fmt.Printf("dry run: the sync would be cancelled for %d queue(s) in vhost '%s'\n", len(queues), vhost)
+			return nil
+		}
+		return postQueueActions(vhost, queues, "cancel_sync")
+	},
+}
+
+func validateQueueSyncFlags(cmd *cobra.Command, args []string) error {
+	if len(api.Page.Name) == 0 {
+		return fmt.Errorf("--name ( or -n ) is a required parameter")
+	}
+	if queueSyncInterval <= 0 {
+		return fmt.Errorf("--interval must be greater than 0")
+	}
+	return nil
+}
+
+// unsynchronisedQueues returns the selected mirrored queues that have unsynchronised mirrors,
+// the mirror state of the selected mirrored queues is printed
+func unsynchronisedQueues(vhost string) ([]*queueInfo, error) {
+	queues, err := selectQueues(vhost)
+	if err != nil {
+		return nil, err
+	}
+
+	mirrored := []*queueInfo{}
+	unsynchronised := []*queueInfo{}
+	for _, q := range queues {
+		if len(q.SlaveNodes) == 0 {
+			continue
+		}
+		mirrored = append(mirrored, q)
+		if len(unsynchronisedMirrors(q)) > 0 {
+			unsynchronised = append(unsynchronised, q)
+		}
+	}
+
+	if len(mirrored) == 0 {
+		fmt.Printf("no mirrored queues in vhost '%s' match '%s'\n", vhost, api.Page.Name)
+		return unsynchronised, nil
+	}
+	printQueueMirrors(mirrored)
+	if len(unsynchronised) == 0 {
+		fmt.Println("all mirrors are synchronised")
+	}
+	return unsynchronised, nil
+}
+
+// unsynchronisedMirrors returns the mirror nodes of the queue that are not synchronised
+func unsynchronisedMirrors(q *queueInfo) []string {
+	nodes := []string{}
+	for _, node := range q.SlaveNodes {
+		if !contains(q.SynchronisedSlaveNodes, node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// printQueueMirrors prints a table with the mirror state of the queues
+func printQueueMirrors(queues []*queueInfo) {
+	rows := [][]string{}
+	for _, q := range queues {
+		unsynchronised := unsynchronisedMirrors(q)
+		rows = append(rows, []string{
+			q.Name,
+			fmt.Sprint(len(q.SlaveNodes)),
+			fmt.Sprint(len(q.SlaveNodes) - len(unsynchronised)),
+			strings.Join(unsynchronised, ","),
+		})
+	}
+	printTable([]string{"NAME", "MIRRORS", "SYNCHRONISED", "UNSYNCHRONISED"}, rows)
+}
+
+// postQueueActions posts the action for each of the queues
+func postQueueActions(vhost string, queues []*queueInfo, action string) error {
+	failed := 0
+	for _, q := range queues {
+		req := api.PostQueueActionsForVhost(vhost, q.Name).Body(map[string]interface{}{"action": action})
+		if _, err := execute(req, nil); err != nil {
+			failed++
+			fmt.Printf("failed to %s queue '%s': %s\n", action, q.Name, errorReason(err))
+			continue
+		}
+		fmt.Printf("%s started for queue '%s'\n", action, q.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d queue(s) failed to %s", failed, len(queues), action)
+	}
+	return nil
+}
+
+// waitForQueueSync polls the queues until all their mirrors are synchronised, or the timeout expires
+func waitForQueueSync(vhost string, queues []*queueInfo) error {
+	deadline := time.Now().Add(queueSyncTimeout)
+	for {
+		time.Sleep(queueSyncInterval)
+
+		mirrors, synchronised := 0, 0
+		for _, q := range queues {
+			current, err := getQueue(vhost, q.Name)
+			if err != nil {
+				return err
+			}
+			mirrors += len(current.SlaveNodes)
+			synchronised += len(current.SlaveNodes) - len(unsynchronisedMirrors(current))
+		}
+		fmt.Printf("%s synchronised %d of %d mirror(s)\n", time.Now().Format("15:04:05"), synchronised, mirrors)
+
+		if synchronised == mirrors {
+			return nil
+		}
+		if queueSyncTimeout > 0 && time.Now().After(deadline) {
+			return fmt.Errorf("%d mirror(s) are not synchronised after %s", mirrors-synchronised, queueSyncTimeout)
+		}
+	}
+}
+
+var (
+	queueSyncDryRun   bool
+	queueSyncWait     bool
+	queueSyncInterval time.Duration
+	queueSyncTimeout  time.Duration
+)
+
+func init() {
+	queueCmd.AddCommand(queueSyncCmd)
+	api.AddPagingFlags(queueSyncCmd)
+	flags := queueSyncCmd.PersistentFlags()
+	flags.BoolVar(&queueSyncDryRun, "dry-run", false, "Only print the mirror state of the queues")
+	flags.BoolVar(&queueSyncWait, "wait", false, "Wait until all mirrors are synchronised")
+	flags.DurationVar(&queueSyncInterval, "interval", 2*time.Second, "The interval between progress updates")
+	flags.DurationVar(&queueSyncTimeout, "timeout", 0, "Stop waiting after this duration, 0 disables the timeout")
+
+	queueCmd.AddCommand(queueCancelSyncCmd)
+	api.AddPagingFlags(queueCancelSyncCmd)
+	queueCancelSyncCmd.PersistentFlags().BoolVar(&queueSyncDryRun, "dry-run", false, "Only print the mirror state of the queues")
+}
@@ -0,0 +1,197 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// rebalanceCmd represents the rebalance command
+var rebalanceCmd = &cobra.Command{
+	Use:   "rebalance",
+	Short: "Rebalances the queue leaders over the nodes of the cluster",
+	Long: `Rebalances the queue leaders over the nodes of the cluster.
+
+The distribution of the queue leaders over the running nodes is determined, the rebalance is started,
+and the distribution is polled every --interval until it has not changed for 3 polls, or the --timeout expires.
+The distribution is only considered stable after it changed, or after 10 seconds when no leaders are moved.
+A table with the number of queue leaders per node before and after the rebalance is printed.
+
+With --plan-only, only the current distribution and skew are printed.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if rebalanceInterval <= 0 {
+			return fmt.Errorf("--interval must be greater than 0")
+		}
+		if rebalanceTimeout <= 0 {
+			return fmt.Errorf("--timeout must be greater than 0")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		before, err := leaderDistribution()
+		if err != nil {
+			return err
+		}
+
+		if rebalancePlanOnly {
+			printLeaderDistribution([]string{"LEADERS"}, before)
+			fmt.Printf("skew: %d\n", leaderSkew(before))
+			return nil
+		}
+
+		if _, err := execute(api.PostRebalanceQueues(), nil); err != nil {
+			return err
+		}
+		fmt.Printf("rebalance started, skew before: %d\n", leaderSkew(before))
+
+		// the rebalance runs in the background, so the distribution is considered stable when
+		// it has not changed for a number of consecutive polls, after it changed at least once,
+		// or after the minimum wait when the rebalance does not move any leaders
+		after := before
+		unchanged := 0
+		changed := false
+		started := time.Now()
+		deadline := started.Add(rebalanceTimeout)
+		for {
+			time.Sleep(rebalanceInterval)
+			current, err := leaderDistribution()
+			if err != nil {
+				return err
+			}
+			if reflect.DeepEqual(current, after) {
+				unchanged++
+			} else {
+				unchanged = 0
+				changed = true
+				after = current
+			}
+			if unchanged >= rebalanceStablePolls && (changed || time.Since(started) >= rebalanceMinimumWait) {
+				if !changed {
+					fmt.Println("no queue leaders were moved")
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				fmt.Printf("the distribution did not stabilise within %s\n", rebalanceTimeout)
+				break
+			}
+		}
+
+		printLeaderDistribution([]string{"BEFORE", "AFTER"}, before, after)
+		fmt.Printf("skew before: %d, after: %d\n", leaderSkew(before), leaderSkew(after))
+		return nil
+	},
+}
+
+const (
+	// rebalanceStablePolls is the number of polls the distribution must be unchanged to be considered stable
+	rebalanceStablePolls = 3
+	// rebalanceMinimumWait is how long to wait for the first change of the distribution before it is considered stable
+	rebalanceMinimumWait = 10 * time.Second
+)
+
+// nodeInfo contains the node details returned by the api
+type nodeInfo struct {
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+}
+
+// leaderDistribution returns the number of queue leaders per node, running nodes without leaders are included
+func leaderDistribution() (map[string]int, error) {
+	nodes := []*nodeInfo{}
+	if _, err := execute(api.GetNodes(), &nodes); err != nil {
+		return nil, err
+	}
+	queues := []*queueInfo{}
+	if _, err := execute(api.GetQueues(), &queues); err != nil {
+		return nil, err
+	}
+
+	distribution := map[string]int{}
+	for _, n := range nodes {
+		if n.Running {
+			distribution[n.Name] = 0
+		}
+	}
+	for _, q := range queues {
+		distribution[q.Node]++
+	}
+	return distribution, nil
+}
+
+// leaderSkew returns the difference between the highest and lowest number of leaders on a node
+func leaderSkew(distribution map[string]int) int {
+	first := true
+	lowest, highest := 0, 0
+	for _, count := range distribution {
+		if first || count < lowest {
+			lowest = count
+		}
+		if first || count > highest {
+			highest = count
+		}
+		first = false
+	}
+	return highest - lowest
+}
+
+// printLeaderDistribution prints a table with the number of leaders per node for each distribution
+func printLeaderDistribution(columns []string, distributions ...map[string]int) {
+	nodes := []string{}
+	seen := map[string]bool{}
+	for _, d := range distributions {
+		for node := range d {
+			if !seen[node] {
+				seen[node] = true
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	sort.Strings(nodes)
+
+	rows := [][]string{}
+	for _, node := range nodes {
+		row := []string{node}
+		for _, d := range distributions {
+			row = append(row, strconv.Itoa(d[node]))
+		}
+		rows = append(rows, row)
+	}
+	printTable(append([]string{"NODE"}, columns...), rows)
+}
+
+var (
+	rebalancePlanOnly bool
+	rebalanceInterval time.Duration
+	rebalanceTimeout  time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(rebalanceCmd)
+	flags := rebalanceCmd.PersistentFlags()
+	flags.BoolVar(&rebalancePlanOnly, "plan-only", false, "Only print the current distribution of the queue leaders")
+	flags.DurationVar(&rebalanceInterval, "interval", 2*time.Second, "The interval between polls of the distribution")
+	flags.DurationVar(&rebalanceTimeout, "timeout", 2*time.Minute, "Stop polling when the distribution has not stabilised after this duration")
+}
@@ -0,0 +1,422 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ruleExpression is a compiled expression of a governance rule, evaluated against the fields of an object
+type ruleExpression interface {
+	eval(fields map[string]interface{}) interface{}
+}
+
+type (
+	literalExpression struct {
+		value interface{}
+	}
+
+	pathExpression struct {
+		path []string
+	}
+
+	hasExpression struct {
+		path *pathExpression
+	}
+
+	notExpression struct {
+		operand ruleExpression
+	}
+
+	logicalExpression struct {
+		and         bool
+		left, right ruleExpression
+	}
+
+	compareExpression struct {
+		operator    string
+		left, right ruleExpression
+	}
+
+	matchExpression struct {
+		negate  bool
+		operand ruleExpression
+		pattern *regexp.Regexp
+	}
+)
+
+func (e *literalExpression) eval(fields map[string]interface{}) interface{} {
+	return e.value
+}
+
+func (e *pathExpression) eval(fields map[string]interface{}) interface{} {
+	var value interface{} = fields
+	for _, name := range e.path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[name]
+	}
+	return value
+}
+
+func (e *hasExpression) eval(fields map[string]interface{}) interface{} {
+	return e.path.eval(fields) != nil
+}
+
+func (e *notExpression) eval(fields map[string]interface{}) interface{} {
+	return !truthy(e.operand.eval(fields))
+}
+
+func (e *logicalExpression) eval(fields map[string]interface{}) interface{} {
+	left := truthy(e.left.eval(fields))
+	if e.and != left {
+		// false && x, and true || x, do not evaluate x
+		return left
+	}
+	return truthy(e.right.eval(fields))
+}
+
+func (e *compareExpression) eval(fields map[string]interface{}) interface{} {
+	left, right := e.left.eval(fields), e.right.eval(fields)
+	switch e.operator {
+	case "==":
+		return reflect.DeepEqual(left, right)
+	case "!=":
+		return !reflect.DeepEqual(left, right)
+	}
+
+	// the ordering operators only compare two numbers, or two strings
+	var c int
+	if l, ok := left.(float64); ok {
+		r, ok := right.(float64)
+		if !ok {
+			return false
+		}
+		switch {
+		case l < r:
+			c = -1
+		case l > r:
+			c = 1
+		}
+	} else if l, ok := left.(string); ok {
+		r, ok := right.(string)
+		if !ok {
+			return false
+		}
+		c = strings.Compare(l, r)
+	} else {
+		return false
+	}
+	switch e.operator {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+func (e *matchExpression) eval(fields map[string]interface{}) interface{} {
+	value, ok := e.operand.eval(fields).(string)
+	return (ok && e.pattern.MatchString(value)) != e.negate
+}
+
+// truthy returns false for null, false, an empty string and zero, and true for all other values
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return len(v) > 0
+	case float64:
+		return v != 0
+	}
+	return true
+}
+
+// ruleToken is a token of a rule expression
+type ruleToken struct {
+	kind     string // "path", "string", "number", "operator" or "end"
+	text     string
+	position int
+}
+
+var ruleOperators = []string{"==", "!=", "=~", "!~", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")"}
+
+// tokenizeRule splits the expression in tokens, paths are names separated by dots,
+// names contain letters, digits, '_' and '-', so argument names like x-max-length can be used
+func tokenizeRule(expression string) ([]*ruleToken, error) {
+	tokens := []*ruleToken{}
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '"' || c == '\'':
+			// a backslash only escapes the quote and itself, so regular expressions can be written as is
+			text := &strings.Builder{}
+			j := i + 1
+			for ; j < len(expression) && expression[j] != c; j++ {
+				if expression[j] == '\\' && j+1 < len(expression) && (expression[j+1] == c || expression[j+1] == '\\') {
+					j++
+				}
+				text.WriteByte(expression[j])
+			}
+			if j >= len(expression) {
+				return nil, fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			tokens = append(tokens, &ruleToken{"string", text.String(), i + 1})
+			i = j + 1
+
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(expression) && expression[i+1] >= '0' && expression[i+1] <= '9':
+			j := i + 1
+			for j < len(expression) && (expression[j] >= '0' && expression[j] <= '9' || expression[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, &ruleToken{"number", expression[i:j], i + 1})
+			i = j
+
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+			for j < len(expression) && isRuleNameChar(expression[j]) {
+				j++
+			}
+			tokens = append(tokens, &ruleToken{"path", expression[i:j], i + 1})
+			i = j
+
+		default:
+			operator := ""
+			for _, o := range ruleOperators {
+				if strings.HasPrefix(expression[i:], o) {
+					operator = o
+					break
+				}
+			}
+			if len(operator) == 0 {
+				return nil, fmt.Errorf("unexpected character '%c' at position %d", c, i+1)
+			}
+			tokens = append(tokens, &ruleToken{"operator", operator, i + 1})
+			i += len(operator)
+		}
+	}
+	return append(tokens, &ruleToken{"end", "", len(expression) + 1}), nil
+}
+
+func isRuleNameChar(c byte) bool {
+	return c == '_' || c == '-' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// ruleParser is a recursive descent parser for rule expressions, from the lowest to the highest precedence:
+//
+//	or         = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | comparison
+//	comparison = primary [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) primary | ( "=~" | "!~" ) string ]
+//	primary    = "(" or ")" | "has" "(" path ")" | string | number | "true" | "false" | "null" | path
+type ruleParser struct {
+	tokens []*ruleToken
+	next   int
+}
+
+// parseRule compiles the rule expression
+func parseRule(expression string) (ruleExpression, error) {
+	tokens, err := tokenizeRule(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %v", expression, err)
+	}
+	p := &ruleParser{tokens: tokens}
+	e, err := p.or()
+	if err == nil && p.peek().kind != "end" {
+		err = p.unexpected()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %v", expression, err)
+	}
+	return e, nil
+}
+
+func (p *ruleParser) peek() *ruleToken {
+	return p.tokens[p.next]
+}
+
+func (p *ruleParser) take() *ruleToken {
+	t := p.tokens[p.next]
+	if t.kind != "end" {
+		p.next++
+	}
+	return t
+}
+
+func (p *ruleParser) accept(operator string) bool {
+	if t := p.peek(); t.kind == "operator" && t.text == operator {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *ruleParser) unexpected() error {
+	t := p.peek()
+	if t.kind == "end" {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected '%s' at position %d", t.text, t.position)
+}
+
+func (p *ruleParser) or() (ruleExpression, error) {
+	left, err := p.and()
+	for err == nil && p.accept("||") {
+		var right ruleExpression
+		if right, err = p.and(); err == nil {
+			left = &logicalExpression{and: false, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *ruleParser) and() (ruleExpression, error) {
+	left, err := p.unary()
+	for err == nil && p.accept("&&") {
+		var right ruleExpression
+		if right, err = p.unary(); err == nil {
+			left = &logicalExpression{and: true, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *ruleParser) unary() (ruleExpression, error) {
+	if p.accept("!") {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpression{operand}, nil
+	}
+	return p.comparison()
+}
+
+func (p *ruleParser) comparison() (ruleExpression, error) {
+	left, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	if t.kind != "operator" {
+		return left, nil
+	}
+	switch t.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.take()
+		right, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+		return &compareExpression{operator: t.text, left: left, right: right}, nil
+	case "=~", "!~":
+		p.take()
+		if p.peek().kind != "string" {
+			return nil, fmt.Errorf("expected a regular expression string after '%s' at position %d", t.text, t.position)
+		}
+		s := p.take()
+		pattern, err := regexp.Compile(s.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %d: %v", s.position, err)
+		}
+		return &matchExpression{negate: t.text == "!~", operand: left, pattern: pattern}, nil
+	}
+	return left, nil
+}
+
+func (p *ruleParser) primary() (ruleExpression, error) {
+	t := p.peek()
+	switch t.kind {
+	case "operator":
+		if !p.accept("(") {
+			return nil, p.unexpected()
+		}
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, p.unexpected()
+		}
+		return e, nil
+
+	case "string":
+		p.take()
+		return &literalExpression{t.text}, nil
+
+	case "number":
+		p.take()
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' at position %d", t.text, t.position)
+		}
+		return &literalExpression{value}, nil
+
+	case "path":
+		p.take()
+		switch t.text {
+		case "true":
+			return &literalExpression{true}, nil
+		case "false":
+			return &literalExpression{false}, nil
+		case "null":
+			return &literalExpression{nil}, nil
+		case "has":
+			if p.accept("(") {
+				if p.peek().kind != "path" {
+					return nil, p.unexpected()
+				}
+				e, err := newPathExpression(p.take())
+				if err != nil {
+					return nil, err
+				}
+				if !p.accept(")") {
+					return nil, p.unexpected()
+				}
+				return &hasExpression{e}, nil
+			}
+		}
+		return newPathExpression(t)
+	}
+	return nil, p.unexpected()
+}
+
+func newPathExpression(t *ruleToken) (*pathExpression, error) {
+	path := strings.Split(t.text, ".")
+	for _, name := range path {
+		if len(name) == 0 {
+			return nil, fmt.Errorf("invalid path '%s' at position %d", t.text, t.position)
+		}
+	}
+	return &pathExpression{path}, nil
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func Test_parseRule(t *testing.T) {
+	fields := map[string]interface{}{
+		"name":    "orders.created.v1",
+		"durable": false,
+		"type":    "quorum",
+		"arguments": map[string]interface{}{
+			"x-max-length": float64(1000),
+			"x-queue-type": "quorum",
+		},
+		"tags": []interface{}{"a", "b"},
+	}
+
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{`name =~ "^[a-z]+\.[a-z-]+\.v\d+$"`, true},
+		{`name !~ "^[a-z]+\.[a-z-]+\.v\d+$"`, false},
+		{`name == 'orders.created.v1'`, true},
+		{`type != "quorum"`, false},
+		{`durable`, false},
+		{`!durable`, true},
+		{`durable || has(arguments.x-max-length)`, true},
+		{`!durable && arguments.x-max-length >= 1000`, true},
+		{`arguments.x-max-length < 1000`, false},
+		{`arguments.x-max-length > -1`, true},
+		{`has(arguments.x-dead-letter-exchange)`, false},
+		{`arguments.x-dead-letter-exchange == null`, true},
+		{`missing.nested.path =~ ".*"`, false},
+		{`missing.nested.path !~ ".*"`, true},
+		{`name < "p"`, true},
+		{`name < 1`, false},
+		{`!(type == "quorum" || type == "stream") || arguments.x-queue-type == type`, true},
+		{`true && !false && (1 == 1.0)`, true},
+		{`name == "a \"quoted\" \\ value"`, false},
+		{`has(tags) && durable == false`, true},
+	}
+	for _, test := range tests {
+		e, err := parseRule(test.expression)
+		if err != nil {
+			t.Errorf("parseRule(%s) failed: %v", test.expression, err)
+			continue
+		}
+		if actual := truthy(e.eval(fields)); actual != test.expected {
+			t.Errorf("%s = %v, expected %v", test.expression, actual, test.expected)
+		}
+	}
+}
+
+func Test_parseRule_Errors(t *testing.T) {
+	invalid := []string{
+		``,
+		`name ==`,
+		`name =~ other`,
+		`name =~ "("`,
+		`(durable`,
+		`durable)`,
+		`has(1)`,
+		`has(name`,
+		`name..first == 1`,
+		`name = "a"`,
+		`name == "a`,
+		`durable && || true`,
+		`name $ 1`,
+	}
+	for _, expression := range invalid {
+		if _, err := parseRule(expression); err == nil {
+			t.Errorf("parseRule(%s) succeeded, expected an error", expression)
+		}
+	}
+}
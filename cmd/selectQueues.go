@@ -0,0 +1,89 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/LogiqsAgro/rmq/api"
+)
+
+type (
+	// queueInfo contains the fields of the queue details returned by the api that are used by the queue commands
+	queueInfo struct {
+		Name                   string                 `json:"name"`
+		VHost                  string                 `json:"vhost"`
+		Type                   string                 `json:"type"`
+		Node                   string                 `json:"node"`
+		Durable                bool                   `json:"durable"`
+		AutoDelete             bool                   `json:"auto_delete"`
+		Arguments              map[string]interface{} `json:"arguments"`
+		Messages               int64                  `json:"messages"`
+		MessagesReady          int64                  `json:"messages_ready"`
+		MessagesUnacknowledged int64                  `json:"messages_unacknowledged"`
+		Consumers              int64                  `json:"consumers"`
+		SlaveNodes             []string               `json:"slave_nodes"`
+		SynchronisedSlaveNodes []string               `json:"synchronised_slave_nodes"`
+	}
+)
+
+// selectQueues returns the queues in the vhost selected by the --name and --regex paging flags.
+// Without --regex the name must match a queue exactly, with --regex all queues with a matching name are returned.
+func selectQueues(vhost string) ([]*queueInfo, error) {
+	if !api.Page.UseRegex {
+		q, err := getQueue(vhost, api.Page.Name)
+		if err != nil {
+			return nil, err
+		}
+		return []*queueInfo{q}, nil
+	}
+
+	re, err := regexp.Compile(api.Page.Name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --name regex: %v", err)
+	}
+
+	req := api.GetQueuesForVhost(vhost)
+	api.ApplyConfig(req)
+	queues := []*queueInfo{}
+	if _, err := api.DoList(req, &queues); err != nil {
+		return nil, err
+	}
+
+	// the api only filters by name when paging is used,
+	// so filter here too, to always get the same selection
+	selected := []*queueInfo{}
+	for _, q := range queues {
+		if re.MatchString(q.Name) {
+			selected = append(selected, q)
+		}
+	}
+	return selected, nil
+}
+
+// getQueue returns the details of a single queue
+func getQueue(vhost, name string) (*queueInfo, error) {
+	q := &queueInfo{}
+	if _, err := execute(api.GetQueueForVhost(vhost, name), q); err != nil {
+		if api.IsStatus(err, http.StatusNotFound) {
+			return nil, fmt.Errorf("queue '%s' not found in vhost '%s'", name, vhost)
+		}
+		return nil, err
+	}
+	return q, nil
+}
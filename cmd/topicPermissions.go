@@ -0,0 +1,121 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// topicPermissionsCmd represents the topic-permissions command
+var topicPermissionsCmd = &cobra.Command{
+	Use:   "topic-permissions",
+	Short: "Manages the topic permissions of users in vhosts",
+	Long: `Manages the topic permissions of users in vhosts.
+
+The user whose topic permissions are managed is set with --username, the --user flag is the user
+that is used to log in to the management api.`,
+	Run: nil,
+}
+
+// topicPermissionsSetCmd represents the topic-permissions set command
+var topicPermissionsSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Sets the topic permissions of a user for a topic exchange in the vhost",
+	Long: `Sets the --write and --read topic permissions of the user for the topic --exchange in the vhost.
+
+The topic permissions are regular expressions matched against the routing keys
+of the messages published to, and the bindings of queues to, the exchange.
+RabbitMQ evaluates them as PCRE regular expressions. They are checked as Go regular expressions,
+which do not support all PCRE features, so a permission that cannot be checked is sent as is, with a warning.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(topicPermissionsUsername) == 0 {
+			return fmt.Errorf("--username ( or -u ) is a required parameter")
+		}
+		if len(topicPermissionsExchange) == 0 {
+			return fmt.Errorf("--exchange ( or -e ) is a required parameter")
+		}
+		warnUncheckedRegexes(map[string]string{
+			"write": topicPermissionsWrite,
+			"read":  topicPermissionsRead,
+		})
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		req := api.PutTopicPermissionsForVhostAndUser(api.Config.VHost, topicPermissionsUsername).Body(map[string]interface{}{
+			"exchange": topicPermissionsExchange,
+			"write":    topicPermissionsWrite,
+			"read":     topicPermissionsRead,
+		})
+		if _, err := execute(req, nil); err != nil {
+			return err
+		}
+		fmt.Printf("set topic permissions of user '%s' in vhost '%s' for exchange '%s': write '%s', read '%s'\n",
+			topicPermissionsUsername, api.Config.VHost, topicPermissionsExchange, topicPermissionsWrite, topicPermissionsRead)
+		return nil
+	},
+}
+
+// topicPermissionsClearCmd represents the topic-permissions clear command
+var topicPermissionsClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clears the topic permissions of a user in the vhost",
+	Long:  `Clears the topic permissions of a user for all exchanges in the vhost`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(topicPermissionsUsername) == 0 {
+			return fmt.Errorf("--username ( or -u ) is a required parameter")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if _, err := execute(api.DeleteTopicPermissionsForVhostAndUser(api.Config.VHost, topicPermissionsUsername), nil); err != nil {
+			if api.IsStatus(err, http.StatusNotFound) {
+				return fmt.Errorf("user '%s' has no topic permissions in vhost '%s'", topicPermissionsUsername, api.Config.VHost)
+			}
+			return err
+		}
+		fmt.Printf("cleared topic permissions of user '%s' in vhost '%s'\n", topicPermissionsUsername, api.Config.VHost)
+		return nil
+	},
+}
+
+var (
+	topicPermissionsUsername string
+	topicPermissionsExchange string
+	topicPermissionsWrite    string
+	topicPermissionsRead     string
+)
+
+func init() {
+	rootCmd.AddCommand(topicPermissionsCmd)
+
+	topicPermissionsCmd.AddCommand(topicPermissionsSetCmd)
+	flags := topicPermissionsSetCmd.PersistentFlags()
+	flags.StringVarP(&topicPermissionsUsername, "username", "u", "", "The user to set the topic permissions for")
+	flags.StringVarP(&topicPermissionsExchange, "exchange", "e", "", "The topic exchange the permissions apply to")
+	flags.StringVar(&topicPermissionsWrite, "write", "", "The regular expression for the routing keys the user can publish with")
+	flags.StringVar(&topicPermissionsRead, "read", "", "The regular expression for the routing keys the user can bind queues with")
+
+	topicPermissionsCmd.AddCommand(topicPermissionsClearCmd)
+	topicPermissionsClearCmd.PersistentFlags().StringVarP(&topicPermissionsUsername, "username", "u", "", "The user to clear the topic permissions for")
+}
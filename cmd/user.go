@@ -0,0 +1,290 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// userCmd represents the user command
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manages RabbitMQ users",
+	Long:  ``,
+	Run:   nil,
+}
+
+// userAddCmd represents the user add command
+var userAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Adds a user",
+	Long: `Adds the user with the given --name.
+
+The password is prompted for without echoing it, or read from stdin when --password-stdin is used.
+The password is hashed locally, only the salted hash is sent to RabbitMQ.
+Use --no-password for users that authenticate with another mechanism, like client certificates.`,
+	PreRunE: validateUserFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		_, err := getUser(userName)
+		if err == nil {
+			return fmt.Errorf("user '%s' already exists, use 'rmq user update' to change it", userName)
+		}
+		if !api.IsStatus(err, http.StatusNotFound) {
+			return err
+		}
+
+		body, err := userBody()
+		if err != nil {
+			return err
+		}
+		body["tags"] = strings.Join(userTagsFlag, ",")
+
+		if _, err := execute(api.PutUser(userName).Body(body), nil); err != nil {
+			return err
+		}
+		fmt.Printf("user '%s' added\n", userName)
+		return nil
+	},
+}
+
+// userUpdateCmd represents the user update command
+var userUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Updates the tags or password of a user",
+	Long: `Updates the tags and/or the password of the user with the given --name.
+
+The tags are replaced when --tags is used, and the password is changed when --change-password,
+--password-stdin or --no-password is used, the other settings of the user are kept.
+The new password is hashed locally, only the salted hash is sent to RabbitMQ.`,
+	PreRunE: validateUserFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		user, err := getUser(userName)
+		if err != nil {
+			if api.IsStatus(err, http.StatusNotFound) {
+				return fmt.Errorf("user '%s' not found", userName)
+			}
+			return err
+		}
+
+		changePassword := userChangePassword || userPasswordStdin || userNoPassword
+		body := map[string]interface{}{
+			"password_hash":     user.PasswordHash,
+			"hashing_algorithm": user.HashingAlgorithm,
+			"tags":              strings.Join(user.Tags, ","),
+		}
+		if changePassword {
+			if body, err = userBody(); err != nil {
+				return err
+			}
+			body["tags"] = strings.Join(user.Tags, ",")
+		}
+		if cmd.Flags().Changed("tags") {
+			body["tags"] = strings.Join(userTagsFlag, ",")
+		}
+
+		if _, err := execute(api.PutUser(userName).Body(body), nil); err != nil {
+			return err
+		}
+		fmt.Printf("user '%s' updated\n", userName)
+		return nil
+	},
+}
+
+// userDeleteCmd represents the user delete command
+var userDeleteCmd = &cobra.Command{
+	Use:   "delete [name...]",
+	Short: "Deletes one or more users",
+	Long:  `Deletes the user with the given --name, or all users named in the arguments.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(userName) == 0 && len(args) == 0 {
+			return fmt.Errorf("specify the user to delete with --name ( or -n ), or the users to delete as arguments")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		names := args
+		if len(userName) > 0 {
+			names = append(names, userName)
+		}
+
+		if len(names) == 1 {
+			if _, err := execute(api.DeleteUser(names[0]), nil); err != nil {
+				if api.IsStatus(err, http.StatusNotFound) {
+					return fmt.Errorf("user '%s' not found", names[0])
+				}
+				return err
+			}
+		} else {
+			if _, err := execute(api.PostUsersBulkDelete().Body(map[string]interface{}{"users": names}), nil); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("deleted user(s) %s\n", strings.Join(names, ", "))
+		return nil
+	},
+}
+
+// userListWithoutPermissionsCmd represents the user list-without-permissions command
+var userListWithoutPermissionsCmd = &cobra.Command{
+	Use:   "list-without-permissions",
+	Short: "Lists the users that do not have access to any vhost",
+	Long:  `Lists the users that do not have access to any vhost`,
+	RunE: RunE(func(cmd *cobra.Command, args []string) (api.Builder, error) {
+		return api.GetUsersWithoutPermissions(), nil
+	}),
+}
+
+type (
+	// userInfo contains the user details returned by the api
+	userInfo struct {
+		Name             string   `json:"name"`
+		PasswordHash     string   `json:"password_hash"`
+		HashingAlgorithm string   `json:"hashing_algorithm"`
+		Tags             userTags `json:"tags"`
+	}
+
+	// userTags are the tags of a user, RabbitMQ 3.9 returns the tags
+	// as a comma separated string, later versions return a list
+	userTags []string
+)
+
+func (t *userTags) UnmarshalJSON(data []byte) error {
+	tags := []string{}
+	if err := json.Unmarshal(data, &tags); err == nil {
+		*t = tags
+		return nil
+	}
+
+	s := ""
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*t = userTags{}
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); len(tag) > 0 {
+			*t = append(*t, tag)
+		}
+	}
+	return nil
+}
+
+// getUser returns the details of the user
+func getUser(name string) (*userInfo, error) {
+	user := &userInfo{}
+	_, err := execute(api.GetUser(name), user)
+	return user, err
+}
+
+// userBody returns the password_hash and hashing_algorithm fields of the user body,
+// the password is prompted for, or read from stdin
+func userBody() (map[string]interface{}, error) {
+	algorithm := hashingAlgorithms[userHashingAlgorithm]
+	body := map[string]interface{}{
+		"password_hash":     "",
+		"hashing_algorithm": algorithm,
+	}
+	if userNoPassword {
+		return body, nil
+	}
+
+	password, err := readPassword(userPasswordStdin)
+	if err != nil {
+		return nil, err
+	}
+
+	hashed, err := hashPassword(password, algorithm)
+	if err != nil {
+		return nil, err
+	}
+	body["password_hash"] = hashed
+	return body, nil
+}
+
+var userTagValues = []string{"administrator", "monitoring", "policymaker", "management", "impersonator"}
+
+func validateUserFlags(cmd *cobra.Command, args []string) error {
+	if len(userName) == 0 {
+		return fmt.Errorf("--name ( or -n ) is a required parameter")
+	}
+	for _, tag := range userTagsFlag {
+		if !contains(userTagValues, tag) {
+			return fmt.Errorf("invalid tag '%s', use one of %s", tag, strings.Join(userTagValues, ", "))
+		}
+	}
+	if _, ok := hashingAlgorithms[userHashingAlgorithm]; !ok {
+		return fmt.Errorf("invalid hashing algorithm '%s', use one of %s", userHashingAlgorithm, strings.Join(hashingAlgorithmNames(), ", "))
+	}
+	if userNoPassword && (userPasswordStdin || userChangePassword) {
+		return fmt.Errorf("--no-password cannot be combined with --password-stdin or --change-password")
+	}
+	return nil
+}
+
+func hashingAlgorithmNames() []string {
+	names := []string{}
+	for name := range hashingAlgorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	userName             string
+	userTagsFlag         []string
+	userHashingAlgorithm string
+	userPasswordStdin    bool
+	userNoPassword       bool
+	userChangePassword   bool
+)
+
+func addUserFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&userName, "name", "n", "", "The user name")
+	flags.StringSliceVar(&userTagsFlag, "tags", []string{}, "The user tags, separated by commas: "+strings.Join(userTagValues, ", "))
+	flags.StringVar(&userHashingAlgorithm, "hashing-algorithm", "sha256", "The password hashing algorithm: "+strings.Join(hashingAlgorithmNames(), ", "))
+	flags.BoolVar(&userPasswordStdin, "password-stdin", false, "Read the password from stdin instead of prompting for it")
+	flags.BoolVar(&userNoPassword, "no-password", false, "The user cannot log in with a password")
+}
+
+func init() {
+	rootCmd.AddCommand(userCmd)
+
+	userCmd.AddCommand(userAddCmd)
+	addUserFlags(userAddCmd)
+
+	userCmd.AddCommand(userUpdateCmd)
+	addUserFlags(userUpdateCmd)
+	userUpdateCmd.PersistentFlags().BoolVar(&userChangePassword, "change-password", false, "Prompt for a new password")
+
+	userCmd.AddCommand(userDeleteCmd)
+	userDeleteCmd.PersistentFlags().StringVarP(&userName, "name", "n", "", "The user name")
+
+	userCmd.AddCommand(userListWithoutPermissionsCmd)
+}
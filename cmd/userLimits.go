@@ -0,0 +1,186 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// userLimitsCmd represents the user-limits command
+var userLimitsCmd = &cobra.Command{
+	Use:   "user-limits",
+	Short: "Manages the connection and channel limits of users",
+	Long: `Manages the maximum number of connections and channels of users.
+
+The user whose limits are managed is set with --username, the --user flag is the user
+that is used to log in to the management api.`,
+	Run: nil,
+}
+
+// userLimitsSetCmd represents the user-limits set command
+var userLimitsSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Sets the maximum number of connections and/or channels of a user",
+	Long:  `Sets the maximum number of connections and/or channels of the user, use -1 for no limit.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(userLimitsUsername) == 0 {
+			return fmt.Errorf("--username ( or -u ) is a required parameter")
+		}
+		if !cmd.Flags().Changed("max-connections") && !cmd.Flags().Changed("max-channels") {
+			return fmt.Errorf("specify --max-connections and/or --max-channels")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		limits := map[string]int{"max-connections": userLimitsMaxConnections, "max-channels": userLimitsMaxChannels}
+		for _, name := range userLimitNames {
+			if !cmd.Flags().Changed(name) {
+				continue
+			}
+			req := api.PutUserLimitForUser(userLimitsUsername, name).Body(map[string]interface{}{"value": limits[name]})
+			if _, err := execute(req, nil); err != nil {
+				if api.IsStatus(err, http.StatusNotFound) {
+					return fmt.Errorf("user '%s' not found", userLimitsUsername)
+				}
+				return err
+			}
+			fmt.Printf("set %s of user '%s' to %d\n", name, userLimitsUsername, limits[name])
+		}
+		return nil
+	},
+}
+
+// userLimitsClearCmd represents the user-limits clear command
+var userLimitsClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clears the connection and/or channel limits of a user",
+	Long:  `Clears the --max-connections and/or --max-channels limits of the user, or both when neither is specified.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(userLimitsUsername) == 0 {
+			return fmt.Errorf("--username ( or -u ) is a required parameter")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		clearAll := !cmd.Flags().Changed("max-connections") && !cmd.Flags().Changed("max-channels")
+		for _, name := range userLimitNames {
+			if !clearAll && !cmd.Flags().Changed(name) {
+				continue
+			}
+			if _, err := execute(api.DeleteUserLimitForUser(userLimitsUsername, name), nil); err != nil && !api.IsStatus(err, http.StatusNotFound) {
+				return err
+			}
+			fmt.Printf("cleared %s of user '%s'\n", name, userLimitsUsername)
+		}
+		return nil
+	},
+}
+
+// userLimitsListCmd represents the user-limits list command
+var userLimitsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the user limits, and the current number of connections and channels of the users",
+	Long: `Lists the connection and channel limits of all users, or of the user given with --username.
+
+The current number of connections and channels of each user is shown next to the limits,
+counted from the open connections.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		req := api.GetUserLimits()
+		if len(userLimitsUsername) > 0 {
+			req = api.GetUserLimits2(userLimitsUsername)
+		}
+		limits := []*userLimits{}
+		if _, err := execute(req, &limits); err != nil {
+			return err
+		}
+
+		connections := []*connectionInfo{}
+		if _, err := execute(api.GetConnections(), &connections); err != nil {
+			return err
+		}
+		connectionCounts := map[string]int{}
+		channelCounts := map[string]int{}
+		for _, c := range connections {
+			connectionCounts[c.User]++
+			channelCounts[c.User] += c.Channels
+		}
+
+		sort.Slice(limits, func(i, j int) bool { return limits[i].User < limits[j].User })
+		rows := [][]string{}
+		for _, l := range limits {
+			rows = append(rows, []string{
+				l.User,
+				strconv.Itoa(connectionCounts[l.User]), formatLimit(l.Value, "max-connections"),
+				strconv.Itoa(channelCounts[l.User]), formatLimit(l.Value, "max-channels"),
+			})
+		}
+		printTable([]string{"USER", "CONNECTIONS", "MAX-CONNECTIONS", "CHANNELS", "MAX-CHANNELS"}, rows)
+		return nil
+	},
+}
+
+// userLimits contains the limits of a user returned by the api
+type userLimits struct {
+	User  string         `json:"user"`
+	Value map[string]int `json:"value"`
+}
+
+var userLimitNames = []string{"max-connections", "max-channels"}
+
+// formatLimit formats the named limit, or '-' when it is not set
+func formatLimit(limits map[string]int, name string) string {
+	if value, ok := limits[name]; ok {
+		return strconv.Itoa(value)
+	}
+	return "-"
+}
+
+var (
+	userLimitsUsername       string
+	userLimitsMaxConnections int
+	userLimitsMaxChannels    int
+)
+
+func init() {
+	rootCmd.AddCommand(userLimitsCmd)
+
+	userLimitsCmd.AddCommand(userLimitsSetCmd)
+	flags := userLimitsSetCmd.PersistentFlags()
+	flags.StringVarP(&userLimitsUsername, "username", "u", "", "The user to set the limits for")
+	flags.IntVar(&userLimitsMaxConnections, "max-connections", -1, "The maximum number of connections, -1 for no limit")
+	flags.IntVar(&userLimitsMaxChannels, "max-channels", -1, "The maximum number of channels, -1 for no limit")
+
+	userLimitsCmd.AddCommand(userLimitsClearCmd)
+	flags = userLimitsClearCmd.PersistentFlags()
+	flags.StringVarP(&userLimitsUsername, "username", "u", "", "The user to clear the limits for")
+	flags.Bool("max-connections", false, "Clear the connection limit")
+	flags.Bool("max-channels", false, "Clear the channel limit")
+
+	userLimitsCmd.AddCommand(userLimitsListCmd)
+	userLimitsListCmd.PersistentFlags().StringVarP(&userLimitsUsername, "username", "u", "", "Only list the limits of this user")
+}
@@ -16,7 +16,13 @@ limitations under the License.
 package cmd
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/LogiqsAgro/rmq/api"
 	"github.com/spf13/cobra"
@@ -53,3 +59,61 @@ func RunE(request func(*cobra.Command, []string) (api.Builder, error)) func(cmd
 		return api.Print(resp, err)
 	}
 }
+
+// execute applies the connection settings to the request, executes it
+// and decodes the json response into result, when result is not nil
+func execute(req api.Builder, result interface{}) (*http.Response, error) {
+	api.Config.Apply(req)
+	return api.DoJson(req, result)
+}
+
+// contains returns true when values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// errorReason returns the reason from a RabbitMQ api error response,
+// or the error message for any other error
+func errorReason(err error) string {
+	if e, ok := err.(*api.ResponseError); ok && len(e.Reason) > 0 {
+		return e.Reason
+	}
+	return err.Error()
+}
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// prompt writes the question to stdout and returns the answer read from stdin
+func prompt(question string) (string, error) {
+	fmt.Print(question)
+	answer, err := stdin.ReadString('\n')
+	if err != nil && (err != io.EOF || len(answer) == 0) {
+		return "", err
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// confirm asks a yes/no question, and returns true when the answer is yes
+func confirm(question string) (bool, error) {
+	answer, err := prompt(question + " [y/N]: ")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
+
+// printTable writes the header and rows to stdout as aligned columns
+func printTable(header []string, rows [][]string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
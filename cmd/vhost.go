@@ -0,0 +1,282 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// vhostCmd represents the vhost command
+var vhostCmd = &cobra.Command{
+	Use:   "vhost",
+	Short: "Manages virtual hosts",
+	Long:  ``,
+	Run:   nil,
+}
+
+// vhostCreateCmd represents the vhost create command
+var vhostCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Creates a vhost, or updates the metadata of an existing vhost",
+	Long: `Creates the vhost with the given --name, or updates the metadata of an existing vhost.
+Only the --description, --tags and --tracing that are given are changed on an existing vhost.
+
+Use --grant to give users full permissions (configure, write and read '.*') in the vhost.`,
+	PreRunE: validateVHostName,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		existing := &vhostMetadata{}
+		exists := true
+		if _, err := execute(api.GetVhost(vhostNameFlag), existing); err != nil {
+			if !api.IsStatus(err, http.StatusNotFound) {
+				return err
+			}
+			exists = false
+		}
+
+		// the metadata of an existing vhost is only changed by the flags that are given
+		flags := cmd.Flags()
+		metadata := map[string]interface{}{
+			"description": vhostDescription,
+			"tags":        strings.Join(vhostTags, ","),
+			"tracing":     vhostTracing,
+		}
+		switch {
+		case !exists:
+			if _, err := execute(api.PutVhost(vhostNameFlag).Body(metadata), nil); err != nil {
+				return err
+			}
+			fmt.Printf("vhost '%s' created\n", vhostNameFlag)
+		case flags.Changed("description") || flags.Changed("tags") || flags.Changed("tracing"):
+			if !flags.Changed("description") {
+				metadata["description"] = existing.Description
+			}
+			if !flags.Changed("tags") {
+				metadata["tags"] = existing.tags()
+			}
+			if !flags.Changed("tracing") {
+				metadata["tracing"] = existing.Tracing
+			}
+			if _, err := execute(api.PutVhost(vhostNameFlag).Body(metadata), nil); err != nil {
+				return err
+			}
+			fmt.Printf("vhost '%s' already exists, updated its metadata\n", vhostNameFlag)
+		default:
+			fmt.Printf("vhost '%s' already exists, its metadata is not changed\n", vhostNameFlag)
+		}
+
+		for _, user := range vhostGrant {
+			req := api.PutPermissionsForVhostAndUser(vhostNameFlag, user).Body(map[string]interface{}{
+				"configure": ".*",
+				"write":     ".*",
+				"read":      ".*",
+			})
+			if _, err := execute(req, nil); err != nil {
+				return fmt.Errorf("failed to grant user '%s' permissions in vhost '%s': %s", user, vhostNameFlag, errorReason(err))
+			}
+			fmt.Printf("granted user '%s' full permissions in vhost '%s'\n", user, vhostNameFlag)
+		}
+		return nil
+	},
+}
+
+// vhostDeleteCmd represents the vhost delete command
+var vhostDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Deletes a vhost, including all its queues, messages and exchanges",
+	Long: `Deletes the vhost with the given --name, including all its queues, messages and exchanges.
+
+The number of queues, messages and connections in the vhost are shown, and you are asked to type
+the name of the vhost to confirm the deletion, unless the name is given with --confirm.`,
+	PreRunE: validateVHostName,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if _, err := execute(api.GetVhost(vhostNameFlag), nil); err != nil {
+			if api.IsStatus(err, http.StatusNotFound) {
+				return fmt.Errorf("vhost '%s' not found", vhostNameFlag)
+			}
+			return err
+		}
+
+		queues := []*queueInfo{}
+		if _, err := execute(api.GetQueuesForVhost(vhostNameFlag), &queues); err != nil {
+			return err
+		}
+		messages := int64(0)
+		for _, q := range queues {
+			messages += q.Messages
+		}
+
+		connections := []json.RawMessage{}
+		if _, err := execute(api.GetConnectionsForVhost(vhostNameFlag), &connections); err != nil {
+			return err
+		}
+
+		fmt.Printf("deleting vhost '%s' will delete %d queue(s) with %d message(s), and close %d connection(s)\n",
+			vhostNameFlag, len(queues), messages, len(connections))
+
+		confirmation := vhostConfirm
+		if len(confirmation) == 0 {
+			answer, err := prompt("Type the name of the vhost to confirm: ")
+			if err != nil {
+				return err
+			}
+			confirmation = answer
+		}
+		if confirmation != vhostNameFlag {
+			return fmt.Errorf("the confirmation '%s' does not match the vhost name '%s', the vhost is not deleted", confirmation, vhostNameFlag)
+		}
+
+		if _, err := execute(api.DeleteVhost(vhostNameFlag), nil); err != nil {
+			return err
+		}
+		fmt.Printf("vhost '%s' deleted\n", vhostNameFlag)
+		return nil
+	},
+}
+
+// vhostSetLimitsCmd represents the vhost set-limits command
+var vhostSetLimitsCmd = &cobra.Command{
+	Use:   "set-limits",
+	Short: "Sets the maximum number of connections and/or queues in a vhost",
+	Long:  `Sets the maximum number of connections and/or queues in the vhost with the given --name, use -1 for no limit.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateVHostName(cmd, args); err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("max-connections") && !cmd.Flags().Changed("max-queues") {
+			return fmt.Errorf("specify --max-connections and/or --max-queues")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		limits := map[string]int{"max-connections": vhostMaxConnections, "max-queues": vhostMaxQueues}
+		for _, name := range vhostLimitNames {
+			if !cmd.Flags().Changed(name) {
+				continue
+			}
+			req := api.PutVhostLimitForVhost(vhostNameFlag, name).Body(map[string]interface{}{"value": limits[name]})
+			if _, err := execute(req, nil); err != nil {
+				return err
+			}
+			fmt.Printf("set %s of vhost '%s' to %d\n", name, vhostNameFlag, limits[name])
+		}
+		return nil
+	},
+}
+
+// vhostClearLimitsCmd represents the vhost clear-limits command
+var vhostClearLimitsCmd = &cobra.Command{
+	Use:     "clear-limits",
+	Short:   "Clears the connection and/or queue limits of a vhost",
+	Long:    `Clears the --max-connections and/or --max-queues limits of the vhost with the given --name, or both when neither is specified.`,
+	PreRunE: validateVHostName,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		clearAll := !cmd.Flags().Changed("max-connections") && !cmd.Flags().Changed("max-queues")
+		for _, name := range vhostLimitNames {
+			if !clearAll && !cmd.Flags().Changed(name) {
+				continue
+			}
+			if _, err := execute(api.DeleteVhostLimitForVhost(vhostNameFlag, name), nil); err != nil && !api.IsStatus(err, http.StatusNotFound) {
+				return err
+			}
+			fmt.Printf("cleared %s of vhost '%s'\n", name, vhostNameFlag)
+		}
+		return nil
+	},
+}
+
+var vhostLimitNames = []string{"max-connections", "max-queues"}
+
+// vhostMetadata is the metadata of a vhost, RabbitMQ 3.9 and later return the tags as a list, older versions as a comma separated string
+type vhostMetadata struct {
+	Description string      `json:"description"`
+	Tags        interface{} `json:"tags"`
+	Tracing     bool        `json:"tracing"`
+}
+
+// tags returns the tags of the vhost as a comma separated string
+func (m *vhostMetadata) tags() string {
+	if list, ok := m.Tags.([]interface{}); ok {
+		tags := []string{}
+		for _, t := range list {
+			tags = append(tags, fmt.Sprint(t))
+		}
+		return strings.Join(tags, ",")
+	}
+	if s, ok := m.Tags.(string); ok {
+		return s
+	}
+	return ""
+}
+
+func validateVHostName(cmd *cobra.Command, args []string) error {
+	if len(vhostNameFlag) == 0 {
+		return fmt.Errorf("--name ( or -n ) is a required parameter")
+	}
+	return nil
+}
+
+var (
+	vhostNameFlag       string
+	vhostDescription    string
+	vhostTags           []string
+	vhostTracing        bool
+	vhostGrant          []string
+	vhostConfirm        string
+	vhostMaxConnections int
+	vhostMaxQueues      int
+)
+
+func init() {
+	rootCmd.AddCommand(vhostCmd)
+
+	vhostCmd.AddCommand(vhostCreateCmd)
+	flags := vhostCreateCmd.PersistentFlags()
+	flags.StringVarP(&vhostNameFlag, "name", "n", "", "The vhost name")
+	flags.StringVar(&vhostDescription, "description", "", "The vhost description")
+	flags.StringSliceVar(&vhostTags, "tags", []string{}, "The vhost tags, separated by commas")
+	flags.BoolVar(&vhostTracing, "tracing", false, "Enable message tracing in the vhost")
+	flags.StringSliceVar(&vhostGrant, "grant", []string{}, "The users to give full permissions in the vhost, separated by commas")
+
+	vhostCmd.AddCommand(vhostDeleteCmd)
+	vhostDeleteCmd.PersistentFlags().StringVarP(&vhostNameFlag, "name", "n", "", "The vhost name")
+	vhostDeleteCmd.PersistentFlags().StringVar(&vhostConfirm, "confirm", "", "The vhost name, to confirm the deletion without being asked")
+
+	vhostCmd.AddCommand(vhostSetLimitsCmd)
+	flags = vhostSetLimitsCmd.PersistentFlags()
+	flags.StringVarP(&vhostNameFlag, "name", "n", "", "The vhost name")
+	flags.IntVar(&vhostMaxConnections, "max-connections", -1, "The maximum number of connections, -1 for no limit")
+	flags.IntVar(&vhostMaxQueues, "max-queues", -1, "The maximum number of queues, -1 for no limit")
+
+	vhostCmd.AddCommand(vhostClearLimitsCmd)
+	flags = vhostClearLimitsCmd.PersistentFlags()
+	flags.StringVarP(&vhostNameFlag, "name", "n", "", "The vhost name")
+	flags.Bool("max-connections", false, "Clear the connection limit")
+	flags.Bool("max-queues", false, "Clear the queue limit")
+}
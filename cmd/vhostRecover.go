@@ -0,0 +1,163 @@
+/*
+Copyright © 2021 Remco Schoeman <remco.schoeman@logiqs.nl>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/LogiqsAgro/rmq/api"
+	"github.com/spf13/cobra"
+)
+
+// vhostRecoverCmd represents the vhost recover command
+var vhostRecoverCmd = &cobra.Command{
+	Use:   "recover",
+	Short: "Restarts the vhosts that are stopped on one or more nodes",
+	Long: `Restarts the vhosts that are stopped on one or more nodes of the cluster.
+
+The cluster_state of all vhosts, or only of the vhost with the given --name, is checked,
+and every vhost that is stopped on a node is restarted on that node. Vhosts on nodes that
+are down, or in another state, cannot be restarted, they are listed as skipped.
+After the restarts the state is checked again, and an error is returned when a vhost
+is still stopped on a node. Use --dry-run to only list the vhosts that are stopped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		stopped, skipped, err := stoppedVhostNodes()
+		if err != nil {
+			return err
+		}
+		printSkippedVhostNodes(skipped)
+		if len(stopped) == 0 {
+			fmt.Println("no vhosts are stopped")
+			return nil
+		}
+
+		printVhostNodes(stopped)
+		if vhostRecoverDryRun {
+			fmt.Printf("dry run: %d vhost(s) would be restarted\n", len(stopped))
+			return nil
+		}
+
+		for _, f := range stopped {
+			if _, err := execute(api.PostVhostStartForNode(f.VHost, f.Node), nil); err != nil {
+				fmt.Printf("failed to start vhost '%s' on node '%s': %s\n", f.VHost, f.Node, errorReason(err))
+				continue
+			}
+			fmt.Printf("started vhost '%s' on node '%s'\n", f.VHost, f.Node)
+		}
+
+		time.Sleep(vhostRecoverWait)
+		stillStopped, _, err := stoppedVhostNodes()
+		if err != nil {
+			return err
+		}
+		if len(stillStopped) > 0 {
+			printVhostNodes(stillStopped)
+			return fmt.Errorf("%d vhost(s) are still stopped", len(stillStopped))
+		}
+		fmt.Println("all stopped vhosts are running")
+		return nil
+	},
+}
+
+type (
+	// vhostInfo contains the vhost details returned by the api
+	vhostInfo struct {
+		Name         string            `json:"name"`
+		ClusterState map[string]string `json:"cluster_state"`
+	}
+
+	// vhostNode is a vhost in a state on a node
+	vhostNode struct {
+		VHost string
+		Node  string
+		State string
+	}
+)
+
+// stoppedVhostNodes returns the vhosts and nodes where the vhost is stopped, and where the vhost
+// is not running for another reason, like a node that is down, and cannot be restarted
+func stoppedVhostNodes() (stopped, skipped []*vhostNode, err error) {
+	vhosts := []*vhostInfo{}
+	if len(vhostNameFlag) > 0 {
+		v := &vhostInfo{}
+		if _, err := execute(api.GetVhost(vhostNameFlag), v); err != nil {
+			return nil, nil, err
+		}
+		vhosts = append(vhosts, v)
+	} else if _, err := execute(api.GetVhosts(), &vhosts); err != nil {
+		return nil, nil, err
+	}
+
+	stopped, skipped = []*vhostNode{}, []*vhostNode{}
+	for _, v := range vhosts {
+		for node, state := range v.ClusterState {
+			switch state {
+			case "running":
+			case "stopped":
+				stopped = append(stopped, &vhostNode{VHost: v.Name, Node: node, State: state})
+			default:
+				skipped = append(skipped, &vhostNode{VHost: v.Name, Node: node, State: state})
+			}
+		}
+	}
+	sortVhostNodes(stopped)
+	sortVhostNodes(skipped)
+	return stopped, skipped, nil
+}
+
+func sortVhostNodes(vhostNodes []*vhostNode) {
+	sort.Slice(vhostNodes, func(i, j int) bool {
+		if vhostNodes[i].VHost != vhostNodes[j].VHost {
+			return vhostNodes[i].VHost < vhostNodes[j].VHost
+		}
+		return vhostNodes[i].Node < vhostNodes[j].Node
+	})
+}
+
+// printVhostNodes prints a table with the state of the vhosts on the nodes
+func printVhostNodes(vhostNodes []*vhostNode) {
+	rows := [][]string{}
+	for _, v := range vhostNodes {
+		rows = append(rows, []string{v.VHost, v.Node, v.State})
+	}
+	printTable([]string{"VHOST", "NODE", "STATE"}, rows)
+}
+
+// printSkippedVhostNodes prints the vhosts that are not restarted, because they are not stopped
+func printSkippedVhostNodes(skipped []*vhostNode) {
+	if len(skipped) == 0 {
+		return
+	}
+	fmt.Printf("skipped %d vhost(s) that are not stopped, the node may be down:\n", len(skipped))
+	printVhostNodes(skipped)
+}
+
+var (
+	vhostRecoverDryRun bool
+	vhostRecoverWait   time.Duration
+)
+
+func init() {
+	vhostCmd.AddCommand(vhostRecoverCmd)
+	flags := vhostRecoverCmd.PersistentFlags()
+	flags.StringVarP(&vhostNameFlag, "name", "n", "", "Only recover the vhost with this name")
+	flags.BoolVar(&vhostRecoverDryRun, "dry-run", false, "Only print the vhosts that are stopped")
+	flags.DurationVar(&vhostRecoverWait, "wait", 2*time.Second, "The time to wait for the vhosts to start before checking their state again")
+}